@@ -16,7 +16,6 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 
 	"github.com/docker/libcompose/project/options"
 
@@ -44,23 +43,29 @@ func init() {
 func build(cmd *cobra.Command, args []string) {
 	// a project name is required
 	if len(projectName) == 0 {
-		cmd.Usage()
-		log.Fatal("Please provide a project name")
+		usageError(cmd, "Please provide a project name")
 	}
 
 	// a file list is required
 	if len(files) == 0 {
-		cmd.Usage()
-		log.Fatal("Please provide a list of compose files to use")
+		usageError(cmd, "Please provide a list of compose files to use")
 	}
 
+	// make sure our config is sane before we do anything with it
+	validateFiles(files)
+
 	// generate our project
-	project, err := control.GenProject(projectName, files, appVersions)
+	project, err := control.GenProject(projectName, files, appVersions, nil)
+	if err != nil {
+		fatal(configError(err))
+	}
+
+	orderedServices, err := project.SortedServices()
 	if err != nil {
-		log.Fatal(err)
+		fatal(configError(err))
 	}
 
-	for _, serviceName := range project.SortedServices() {
+	for _, serviceName := range orderedServices {
 		// if our service has a build component, we build it. Otherwise we skip it.
 		if project.Services[serviceName].Config().Build.Context == "" {
 			fmt.Printf("%v does not have a build section. Skipping.\n", serviceName)
@@ -74,7 +79,7 @@ func build(cmd *cobra.Command, args []string) {
 		})
 
 		if err != nil {
-			log.Fatal(err)
+			fatal(dockerError(fmt.Sprintf("build service %v", serviceName), err))
 		}
 	}
 
@@ -0,0 +1,88 @@
+// Copyright © 2016 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dansteen/controlled-compose/control"
+	"github.com/spf13/cobra"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Print the fully resolved composition",
+	Long: `Print the files that make up the composition in the order they were resolved in, the
+	merged config after image versions have been overridden and monitored folders exported, and the
+	state conditions for each service.  Everything other than the merged config is printed as yaml
+	comments so the output can be used as a compose file.`,
+	Run: configure,
+}
+
+func init() {
+	RootCmd.AddCommand(configCmd)
+	configCmd.Flags().StringSliceVarP(&files, "file", "f", nil, "-f <PathToComposeFile>")
+	configCmd.Flags().StringSliceVarP(&appVersions, "app_version", "a", nil, "The version of a particular container to use.  This will overrid e what is set in the compose files for a particular image or build stanza. Format: container:version")
+}
+
+func configure(cmd *cobra.Command, args []string) {
+	// a file list is required
+	if len(files) == 0 {
+		usageError(cmd, "Please provide a list of compose files to use")
+	}
+	// a project name is required
+	if len(projectName) == 0 {
+		usageError(cmd, "Please provide a project name")
+	}
+
+	// make sure our config is sane before we do anything with it
+	validateFiles(files)
+
+	// generate our project
+	project, err := control.GenProject(projectName, files, appVersions, nil)
+	if err != nil {
+		fatal(configError(err))
+	}
+
+	// the files we read in
+	fmt.Println("# Files in resolution order:")
+	for index, file := range project.Files {
+		fmt.Printf("#   %v. %v\n", index+1, file)
+	}
+
+	// our merged config
+	resolvedConfig, err := project.ResolvedConfig()
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println("#")
+	fmt.Println("# Resolved config:")
+	fmt.Print(string(resolvedConfig))
+
+	// and the state conditions we parsed out of it
+	fmt.Println("#")
+	fmt.Println("# State conditions:")
+	serviceNames := make([]string, 0)
+	for name := range project.StateConditions {
+		serviceNames = append(serviceNames, name)
+	}
+	sort.Strings(serviceNames)
+	for _, name := range serviceNames {
+		fmt.Printf("#   %v:\n", name)
+		fmt.Print(control.DescribeConditions(project.StateConditions[name], "#     "))
+	}
+}
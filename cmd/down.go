@@ -0,0 +1,74 @@
+// Copyright © 2016 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/dansteen/controlled-compose/compose"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+// some variables to store our flags
+var (
+	stopTimeout   int
+	removeVolumes bool
+	removeExports bool
+)
+
+// downCmd represents the down command
+var downCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Stop and remove a compose project",
+	Long: `Stop and remove the containers and networks of a compose project.  Services are
+	stopped in the reverse of the order they are started in.`,
+	Run: down,
+}
+
+func init() {
+	RootCmd.AddCommand(downCmd)
+	downCmd.Flags().StringSliceVarP(&files, "file", "f", nil, "-f <PathToComposeFile>")
+	downCmd.Flags().StringSliceVarP(&appVersions, "app_version", "a", nil, "The version of a particular container to use.  This will overrid e what is set in the compose files for a particular image or build stanza. Format: container:version")
+	downCmd.Flags().IntVarP(&stopTimeout, "timeout", "t", 10, "The number of seconds to wait for each service to stop before killing it")
+	downCmd.Flags().BoolVarP(&removeVolumes, "volumes", "v", false, "Remove the volumes created for the project")
+	downCmd.Flags().BoolVar(&removeExports, "remove-exports", false, "Remove the controlled_compose_<pid> directories created to export monitored files")
+}
+
+func down(cmd *cobra.Command, args []string) {
+	// a file list is required
+	if len(files) == 0 {
+		usageError(cmd, "Please provide a list of compose files to use")
+	}
+	// a project name is required
+	if len(projectName) == 0 {
+		usageError(cmd, "Please provide a project name")
+	}
+
+	// generate our project.  the files are validated as it is read in
+	composition, err := compose.New(projectName, files,
+		compose.WithAppVersions(appVersions...),
+		compose.WithStopTimeout(stopTimeout),
+		compose.WithRemoveVolumes(removeVolumes),
+		compose.WithRemoveExports(removeExports),
+	)
+	if err != nil {
+		fatal(err)
+	}
+
+	// stop our services in the reverse of the order they were started in, and then clean up everything else
+	err = composition.Down(context.Background())
+	if err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"log"
+	"os"
+
+	"github.com/dansteen/controlled-compose/types"
+	"github.com/spf13/cobra"
+)
+
+// the codes we exit with.  These are documented in the README, so scripts can rely on them.
+const (
+	exitSuccess         = 0
+	exitConditionFailed = 1
+	exitConfigError     = 2
+	exitDockerError     = 3
+	exitOtherError      = 4
+	// the code a shell gives a process stopped by SIGINT.  up exits with the code of whichever signal it received
+	exitInterrupted = 130
+)
+
+// exitCode works out what we should exit with for err.  Being interrupted by a signal is handled separately, as it
+// can cause any kind of error on its way out.
+func exitCode(err error) int {
+	switch typed := err.(type) {
+	case nil:
+		return exitSuccess
+	case *types.ConditionFailedError:
+		return exitConditionFailed
+	case *types.ConditionError:
+		// we go by whatever stopped the handler
+		return exitCode(typed.Err)
+	case *types.ConfigError:
+		return exitConfigError
+	case *types.DockerAPIError, *types.NoContainerError:
+		return exitDockerError
+	case *types.InterruptedError:
+		return exitInterrupted
+	default:
+		return exitOtherError
+	}
+}
+
+// fatal prints err and exits with the code that matches it
+func fatal(err error) {
+	log.Print(err)
+	os.Exit(exitCode(err))
+}
+
+// usageError shows how cmd is used and exits because of message
+func usageError(cmd *cobra.Command, message string) {
+	cmd.Usage()
+	fatal(configError(errors.New(message)))
+}
+
+// configError marks err as a problem with our config or arguments
+func configError(err error) error {
+	return &types.ConfigError{Err: err}
+}
+
+// dockerError marks err as a failure of the docker server while doing op
+func dockerError(op string, err error) error {
+	return &types.DockerAPIError{Op: op, Err: err}
+}
@@ -0,0 +1,72 @@
+// Copyright © 2016 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/dansteen/controlled-compose/control"
+	"github.com/spf13/cobra"
+)
+
+// some variables to store our flags
+var (
+	graphFormat string
+)
+
+// graphCmd represents the graph command
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Print the dependency graph of a compose project",
+	Long: `Print the dependency graph of a compose project, with each service annotated with the
+	files it was defined in and its state conditions.  The graph can be printed as dot, mermaid or json.`,
+	Run: printGraph,
+}
+
+func init() {
+	RootCmd.AddCommand(graphCmd)
+	graphCmd.Flags().StringSliceVarP(&files, "file", "f", nil, "-f <PathToComposeFile>")
+	graphCmd.Flags().StringSliceVarP(&appVersions, "app_version", "a", nil, "The version of a particular container to use.  This will overrid e what is set in the compose files for a particular image or build stanza. Format: container:version")
+	graphCmd.Flags().StringVar(&graphFormat, "format", "dot", "The format to print the graph in. One of dot, mermaid or json")
+}
+
+func printGraph(cmd *cobra.Command, args []string) {
+	// a file list is required
+	if len(files) == 0 {
+		usageError(cmd, "Please provide a list of compose files to use")
+	}
+	// a project name is required
+	if len(projectName) == 0 {
+		usageError(cmd, "Please provide a project name")
+	}
+
+	// make sure our config is sane before we do anything with it
+	validateFiles(files)
+
+	// generate our project
+	project, err := control.GenProject(projectName, files, appVersions, nil)
+	if err != nil {
+		fatal(configError(err))
+	}
+
+	nodes, err := project.Graph()
+	if err != nil {
+		fatal(configError(err))
+	}
+	err = control.WriteGraph(os.Stdout, nodes, graphFormat)
+	if err != nil {
+		fatal(configError(err))
+	}
+}
@@ -16,7 +16,6 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
 
 	"github.com/docker/engine-api/types"
@@ -46,19 +45,18 @@ func init() {
 func rm(cmd *cobra.Command, args []string) {
 	// a project name is required
 	if len(projectName) == 0 {
-		cmd.Usage()
-		log.Fatal("Please provide a project name")
+		usageError(cmd, "Please provide a project name")
 	}
 
 	// grab a list of containers that are running
 	dockerClient, err := client.Create(client.Options{})
 	if err != nil {
-		log.Fatal(err)
+		fatal(dockerError("connect to the docker server", err))
 	}
 
 	allContainers, err := dockerClient.ContainerList(context.Background(), types.ContainerListOptions{All: true})
 	if err != nil {
-		log.Fatal(err)
+		fatal(dockerError("list containers", err))
 	}
 
 	// run through and pick out our containers
@@ -76,10 +74,11 @@ func rm(cmd *cobra.Command, args []string) {
 	// first check if we have specified force or if there are no running containers
 	if len(runningContainers) != 0 && force == false {
 		fmt.Println("The following containers are still running. Specify -f to force stop them. No action taken")
+		// we list every running container before we exit, so they can all be dealt with at once
 		for _, container := range runningContainers {
 			fmt.Printf("%v\n", container.Names)
-			os.Exit(1)
 		}
+		os.Exit(exitOtherError)
 	} else {
 		for _, container := range ourContainers {
 			fmt.Printf("Removing %v:  ", container.Names)
@@ -89,7 +88,7 @@ func rm(cmd *cobra.Command, args []string) {
 				Force:         true,
 			})
 			if err != nil {
-				log.Fatal(err)
+				fatal(dockerError(fmt.Sprintf("remove container %v", container.Names), err))
 			}
 			fmt.Printf("%v\n", "done")
 		}
@@ -15,28 +15,42 @@
 package cmd
 
 import (
+	"github.com/dansteen/controlled-compose/compose"
 	"github.com/dansteen/controlled-compose/control"
-	"github.com/dansteen/controlled-compose/handler"
-	"github.com/dansteen/controlled-compose/types"
+	"github.com/dansteen/controlled-compose/reporter"
 	"golang.org/x/net/context"
-	"log"
 
 	"fmt"
-	//	"github.com/docker/libcompose/docker"
-	"github.com/docker/libcompose/docker/client"
-	//"github.com/docker/libcompose/docker/network"
-	//libcomposeProject "github.com/docker/libcompose/project"
-	"github.com/docker/libcompose/project/options"
+	"io"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
+// some variables to store our flags
+var (
+	parallelism     int
+	abortCleanup    bool
+	diagnosticLines int
+	artifactsDir    string
+	outputFormat    string
+	junitReport     string
+	showTimings     bool
+	dryRun          bool
+)
+
+// out is where we write anything meant for people rather than machines
+var out io.Writer = os.Stdout
+
 // upCmd represents the up command
 var upCmd = &cobra.Command{
-	Use:   "up",
+	Use:   "up [service...]",
 	Short: "Bring up a compose project",
-	Long:  `Bring up a compose project`,
+	Long:  `Bring up a compose project.  If services are listed, only they and the services they depend on are started`,
 	Run:   up,
 }
 
@@ -54,6 +68,16 @@ func init() {
 	RootCmd.AddCommand(upCmd)
 	upCmd.Flags().StringSliceVarP(&files, "file", "f", nil, "-f <PathToComposeFile>")
 	upCmd.Flags().StringSliceVarP(&appVersions, "app_version", "a", nil, "The version of a particular container to use.  This will overrid e what is set in the compose files for a particular image or build stanza. Format: container:version")
+	upCmd.Flags().IntVar(&parallelism, "parallelism", 1, "The number of services to start at the same time once their dependencies have succeeded. 0 means no limit")
+	upCmd.Flags().BoolVar(&abortCleanup, "abort-cleanup", false, "Stop and remove the services started during this run if any of them fail or the run is interrupted. Defaults to the abort_cleanup config setting")
+	upCmd.Flags().IntVar(&diagnosticLines, "diagnostic-lines", 50, "The number of lines of output and monitored files to include in the report for a failed service")
+	upCmd.Flags().StringVar(&artifactsDir, "artifacts-dir", "", "A directory to write the report for a failed service to, so it can be collected by CI")
+	upCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "The format to report progress in. One of text or json. With json, newline delimited events are written to STDOUT and everything else to STDERR")
+	upCmd.Flags().StringVar(&junitReport, "junit-report", "", "A path to write a junit report of the startup results of each service to")
+	upCmd.Flags().BoolVar(&showTimings, "timings", false, "Report how long each step of starting each service took, and the critical path through the dependencies, once the run is finished")
+	upCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the order services would be started in, their images, state conditions and exported volumes without contacting docker")
+	upCmd.Flags().IntVarP(&stopTimeout, "timeout", "t", 10, "The number of seconds to wait for each service to stop before killing it when cleaning up")
+	upCmd.Flags().BoolVarP(&removeVolumes, "volumes", "v", false, "Remove the volumes of the services stopped by --abort-cleanup")
 
 }
 
@@ -61,108 +85,159 @@ func up(cmd *cobra.Command, args []string) {
 
 	// a file list is required
 	if len(files) == 0 {
-		cmd.Usage()
-		log.Fatal("Please provide a list of compose files to use")
+		usageError(cmd, "Please provide a list of compose files to use")
 	}
 	// a project name is required
 	if len(projectName) == 0 {
-		cmd.Usage()
-		log.Fatal("Please provide a project name")
+		usageError(cmd, "Please provide a project name")
 	}
 
-	project, err := control.GenProject(projectName, files, appVersions)
-	orderedServices := project.SortedServices()
-	fmt.Printf("Services will be started in the following order: %v\n", orderedServices)
+	// if we were not told whether to clean up on the command line we use our config
+	if !cmd.Flags().Changed("abort-cleanup") {
+		abortCleanup = viper.GetBool("abort_cleanup")
+	}
 
-	// create a connection to the docker server
-	dockerClient, err := client.Create(client.Options{})
+	// when we are writing events for machines we keep everything else off of STDOUT
+	if outputFormat == "json" {
+		out = os.Stderr
+	}
+	runReporter, err := reporter.New(outputFormat, os.Stdout)
 	if err != nil {
-		log.Fatal(err)
+		cmd.Usage()
+		fatal(configError(err))
 	}
 
-	// this does not seem to work
-	//networks, err := network.NetworksFromServices(dockerClient, projectName, project.ComposeProject.(*libcomposeProject.Project).NetworkConfigs, project.ComposeProject.(*libcomposeProject.Project).ServiceConfigs, true)
-	//if err != nil {
-	//		log.Fatal(err)
-	//	}
-	//fmt.Printf("%+v\n", networks)
-
-	// run through and start up our services
-	for _, service_name := range orderedServices {
-		// the return status of our monitors
-		event_response := make(chan types.ContainerStatus)
-		// also an indicator that we no longer need to wait for return status
-		done := make(chan struct{})
+	// the files are validated as the composition is read in, so we do not check them here
+	composition, err := compose.New(projectName, files,
+		compose.WithAppVersions(appVersions...),
+		compose.WithParallelism(parallelism),
+		compose.WithAbortCleanup(abortCleanup),
+		compose.WithStopTimeout(stopTimeout),
+		compose.WithRemoveVolumes(removeVolumes),
+		compose.WithDiagnosticLines(diagnosticLines),
+		compose.WithArtifactsDir(artifactsDir),
+		compose.WithJUnitReport(junitReport),
+		compose.WithTimings(showTimings),
+		compose.WithReporter(runReporter),
+		compose.WithOutput(out),
+	)
+	if err != nil {
+		fatal(err)
+	}
 
-		fmt.Printf("Starting  up service - %v\n", service_name)
-		err = project.ComposeProject.Up(context.Background(), options.Up{options.Create{ForceRecreate: true}}, service_name)
+	// if we are only showing what we would do, we stop before we talk to docker
+	if dryRun {
+		project := composition.Project()
+		// if we were given services we only show them and what they depend on
+		if len(args) > 0 {
+			project, err = project.Select(args)
+			if err != nil {
+				fatal(configError(err))
+			}
+		}
+		orderedServices, err := project.SortedServices()
 		if err != nil {
-			log.Fatal(err)
+			fatal(configError(err))
 		}
-		fmt.Printf("Started up service - %v\n", service_name)
-
-		// get the container name for this service.
-		containers, err := project.Containers(service_name)
+		err = printPlan(project, orderedServices)
 		if err != nil {
-			log.Fatal(err)
+			fatal(configError(err))
 		}
-		// We only spin up one for each services so we can just grab the first one
-		container_name := containers[0].Name()
-		//fmt.Println(containers[0].(*docker.Container).Networks(context.Background()))
-
-		// depending on which monitors this service uses we do different things
-		// first see if there area ny state conditions at all
-		if conditions, found := project.StateConditions[service_name]; found {
-			fmt.Printf("Waiting for conditions: %+v\n", conditions)
-			// check if we monitor the exit code
-			if conditions.ExitCodes != nil {
-				// add in a listener so we can get updates on what is happening
-				//container_events := make(chan events.Event, 2)
-				//p.AddListener(container_events)
-				d_events, err := project.ComposeProject.Events(context.Background(), service_name)
-				if err != nil {
-					log.Fatal(err)
-				}
-				// listen for events
-				go handler.Exit(dockerClient, d_events, event_response, conditions.ExitCodes, done)
-			}
+		return
+	}
 
-			// check if we have configured a timeout
-			if conditions.Timeout != nil {
-				go handler.Timeout(conditions.Timeout, event_response, done)
-			}
+	// an interrupt cancels our context, which stops our monitors and any services that are still starting
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupted := handleSignals(cancel)
 
-			// check if we have  log monitors
-			if conditions.FileMonitors != nil {
-				// run a handler for each file
-				for filename, monitors := range conditions.FileMonitors {
-					// depending on what time of file/output we are monitoring we do things a bit differently
-					if filename == "STDOUT" {
-						go handler.Output(dockerClient, container_name, true, false, monitors, event_response, done)
-					} else if filename == "STDERR" {
-						go handler.Output(dockerClient, container_name, false, true, monitors, event_response, done)
-					} else {
-						go handler.FileMonitor(filename, monitors, event_response, done)
-					}
-				}
-			}
+	// run through and start up our services.  the result of each one has already been reported, so all that is left
+	// is to work out how we exit
+	err = composition.Up(ctx, args...)
+	if err != nil {
+		code := exitCode(err)
+		select {
+		case sig := <-interrupted:
+			code = signalExitCode(sig)
+		default:
+		}
+		os.Exit(code)
+	}
+}
 
-			// wait until we have been given the go-ahead to move on to the next service if we need to
-			fmt.Println("waiting for response")
-			response := <-event_response
-			fmt.Println(response)
-			// we have to be sure to close this as some functions may still be running
-			close(done)
-			close(event_response)
-			// we only continue if we returned success
-			if response.Status != "success" {
-				fmt.Printf("Failed! - Container %v exited with an error: %v", container_name, response.Message)
-				os.Exit(1)
-			}
+// handleSignals cancels our run when we receive SIGINT or SIGTERM, and passes on the signal so we can tell why our
+// run stopped.  A second signal exits straight away in case stopping cleanly is taking too long.
+func handleSignals(cancel context.CancelFunc) <-chan os.Signal {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	interrupted := make(chan os.Signal, 1)
+	go func() {
+		sig := <-signals
+		fmt.Fprintf(out, "Received %v, stopping.  Send it again to exit immediately\n", sig)
+		interrupted <- sig
+		cancel()
+		sig = <-signals
+		os.Exit(signalExitCode(sig))
+	}()
+	return interrupted
+}
+
+// signalExitCode follows the shell convention of exiting with 128 plus the number of the signal that stopped us
+func signalExitCode(sig os.Signal) int {
+	if number, ok := sig.(syscall.Signal); ok {
+		return 128 + int(number)
+	}
+	return exitOtherError
+}
+
+// printPlan shows what up would do: the files that make up our project, the order services would be started in, and
+// for each service its image, state conditions and any volumes we added to export its monitored files
+func printPlan(project *control.Project, orderedServices []string) error {
+	fmt.Fprintf(out, "Files:\n")
+	for _, file := range project.Files {
+		fmt.Fprintf(out, "  %v\n", file)
+	}
+
+	// with a parallelism of 1 services are started one at a time in order, otherwise each wave is started together
+	if parallelism == 1 {
+		fmt.Fprintf(out, "Start order:\n")
+		for index, service_name := range orderedServices {
+			fmt.Fprintf(out, "  %v. %v\n", index+1, service_name)
+		}
+	} else {
+		waves, err := project.Waves()
+		if err != nil {
+			return err
+		}
+		limit := "no limit"
+		if parallelism > 0 {
+			limit = fmt.Sprintf("at most %v at a time", parallelism)
+		}
+		fmt.Fprintf(out, "Start order (%v):\n", limit)
+		for index, wave := range waves {
+			fmt.Fprintf(out, "  %v. %v\n", index+1, strings.Join(wave, ", "))
 		}
 	}
 
-	if err != nil {
-		log.Fatal(err)
+	for _, service_name := range orderedServices {
+		fmt.Fprintf(out, "Service %v:\n", service_name)
+		image := project.Image(service_name)
+		if image == "" {
+			image = "(built from source)"
+		}
+		fmt.Fprintf(out, "  image: %v\n", image)
+		conditions := control.DescribeConditions(project.StateConditions[service_name], "    ")
+		if conditions == "" {
+			fmt.Fprintf(out, "  state conditions: none, the service succeeds once it has started\n")
+		} else {
+			fmt.Fprintf(out, "  state conditions:\n%v", conditions)
+		}
+		if volumes := project.ExportedVolumes[service_name]; len(volumes) > 0 {
+			fmt.Fprintf(out, "  exported volumes:\n")
+			for _, volume := range volumes {
+				fmt.Fprintf(out, "    %v\n", volume)
+			}
+		}
 	}
+	return nil
 }
@@ -0,0 +1,64 @@
+// Copyright © 2016 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dansteen/controlled-compose/control"
+	"github.com/spf13/cobra"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate compose files",
+	Long: `Check the require and state_conditions stanzas of the compose files, and every
+	file they require, for mistakes.`,
+	Run: validate,
+}
+
+func init() {
+	RootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().StringSliceVarP(&files, "file", "f", nil, "-f <PathToComposeFile>")
+}
+
+func validate(cmd *cobra.Command, args []string) {
+	// a file list is required
+	if len(files) == 0 {
+		usageError(cmd, "Please provide a list of compose files to use")
+	}
+
+	validateFiles(files)
+	fmt.Println("Configuration is valid")
+}
+
+// validateFiles checks our compose files, and exits after listing the problems if there are any.  This should be
+// run before anything else is done with the files.
+func validateFiles(files []string) {
+	problems, err := control.Validate(files)
+	if err != nil {
+		fatal(configError(err))
+	}
+	if len(problems) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Found %v problems in the configuration:\n", len(problems))
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "  %v\n", problem)
+	}
+	os.Exit(exitConfigError)
+}
@@ -0,0 +1,87 @@
+// Compose lets go programs, such as integration test suites, bring a controlled compose project up and down
+// without shelling out to the command line tool
+package compose
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/dansteen/controlled-compose/control"
+	"github.com/dansteen/controlled-compose/reporter"
+	"github.com/dansteen/controlled-compose/types"
+	"github.com/docker/engine-api/client"
+	composeClient "github.com/docker/libcompose/docker/client"
+)
+
+// Composition is a compose project that can be brought up and down
+type Composition struct {
+	name     string
+	project  *control.Project
+	settings settings
+	// libcompose projects are not safe to use from more than one goroutine, so services that are started in parallel
+	// take turns with ours
+	composeLock sync.Mutex
+}
+
+// New reads in files, and every file they require, as the project name.  The files are validated first, and a
+// *types.ConfigError is returned if there is anything wrong with them.  We do not talk to docker until we need to.
+//
+// Errors returned by a Composition are a *types.ConfigError when the config or the arguments can not be used, a
+// *types.DockerAPIError when the docker server could not do what we asked, a *types.ConditionFailedError when a
+// service fails its state conditions, and a *types.ConditionError when they could not be decided.
+func New(name string, files []string, options ...Option) (*Composition, error) {
+	s := settings{
+		parallelism:     1,
+		stopTimeout:     10,
+		diagnosticLines: 50,
+		out:             os.Stdout,
+	}
+	for _, option := range options {
+		option(&s)
+	}
+	if s.reporter == nil {
+		s.reporter, _ = reporter.New("text", s.out)
+	}
+
+	// make sure our config is sane before we do anything with it
+	problems, err := control.Validate(files)
+	if err != nil {
+		return nil, &types.ConfigError{Err: err}
+	}
+	if len(problems) > 0 {
+		messages := make([]string, 0, len(problems))
+		for _, problem := range problems {
+			messages = append(messages, fmt.Sprintf("  %v", problem))
+		}
+		return nil, &types.ConfigError{Err: fmt.Errorf("Found %v problems in the configuration:\n%v", len(problems), strings.Join(messages, "\n"))}
+	}
+
+	project, err := control.GenProject(name, files, s.appVersions, s.dockerClient)
+	if err != nil {
+		return nil, &types.ConfigError{Err: err}
+	}
+	return &Composition{
+		name:     name,
+		project:  &project,
+		settings: s,
+	}, nil
+}
+
+// Project returns the project we are managing
+func (c *Composition) Project() *control.Project {
+	return c.project
+}
+
+// docker returns our connection to the docker server, and connects if we have not done so yet
+func (c *Composition) docker() (client.APIClient, error) {
+	if c.settings.dockerClient == nil {
+		created, err := composeClient.Create(composeClient.Options{})
+		if err != nil {
+			return nil, &types.DockerAPIError{Op: "connect to the docker server", Err: err}
+		}
+		c.settings.dockerClient = created
+	}
+	return c.settings.dockerClient, nil
+}
@@ -0,0 +1,57 @@
+package compose
+
+import (
+	"fmt"
+
+	"github.com/dansteen/controlled-compose/control"
+	"github.com/dansteen/controlled-compose/types"
+	"golang.org/x/net/context"
+)
+
+// Down stops and removes our services in the reverse of the order they are started in, and then removes the
+// networks created for them.  Volumes and the directories created to export monitored files are also removed if we
+// were asked to.
+func (c *Composition) Down(ctx context.Context) error {
+	dockerClient, err := c.docker()
+	if err != nil {
+		return err
+	}
+
+	// stop our services in the reverse of the order they were started in
+	reversedServices, err := c.project.ReverseServices()
+	if err != nil {
+		return &types.ConfigError{Err: err}
+	}
+	// our containers are the only record of the directories exported by an earlier run, so we find them first
+	var exportDirs []string
+	if c.settings.removeExports {
+		exportDirs, err = c.project.ExportDirs(ctx, dockerClient)
+		if err != nil {
+			return &types.DockerAPIError{Op: "find exported directories", Err: err}
+		}
+	}
+	fmt.Fprintf(c.settings.out, "Services will be stopped in the following order: %v\n", reversedServices)
+	err = c.project.StopServices(ctx, c.settings.out, reversedServices, c.settings.stopTimeout, c.settings.removeVolumes)
+	if err != nil {
+		return &types.DockerAPIError{Op: "stop services", Err: err}
+	}
+
+	// then clean up everything else
+	err = c.project.RemoveNetworks(ctx, c.settings.out, dockerClient)
+	if err != nil {
+		return &types.DockerAPIError{Op: "remove networks", Err: err}
+	}
+	if c.settings.removeVolumes {
+		err = c.project.RemoveVolumes(ctx, c.settings.out, dockerClient)
+		if err != nil {
+			return &types.DockerAPIError{Op: "remove volumes", Err: err}
+		}
+	}
+	if c.settings.removeExports {
+		err = control.RemoveExports(c.settings.out, exportDirs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
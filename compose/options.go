@@ -0,0 +1,124 @@
+package compose
+
+import (
+	"io"
+
+	"github.com/dansteen/controlled-compose/reporter"
+	"github.com/docker/engine-api/client"
+)
+
+// settings holds everything our options can change.  Each one mirrors a flag of the command line tool.
+type settings struct {
+	appVersions     []string
+	parallelism     int
+	abortCleanup    bool
+	stopTimeout     int
+	diagnosticLines int
+	artifactsDir    string
+	junitReport     string
+	timings         bool
+	removeVolumes   bool
+	removeExports   bool
+	reporter        reporter.Reporter
+	out             io.Writer
+	dockerClient    client.APIClient
+}
+
+// Option changes how a Composition is brought up or down
+type Option func(*settings)
+
+// WithAppVersions overrides the version of the images or builds named in versions.  Format: container:version
+func WithAppVersions(versions ...string) Option {
+	return func(s *settings) {
+		s.appVersions = append(s.appVersions, versions...)
+	}
+}
+
+// WithParallelism sets how many services can be started at the same time once their dependencies have succeeded.
+// 0 means no limit.  Defaults to 1.
+func WithParallelism(parallelism int) Option {
+	return func(s *settings) {
+		s.parallelism = parallelism
+	}
+}
+
+// WithAbortCleanup stops and removes the services started by Up if any of them fail, or if Up is cancelled
+func WithAbortCleanup(abortCleanup bool) Option {
+	return func(s *settings) {
+		s.abortCleanup = abortCleanup
+	}
+}
+
+// WithStopTimeout sets how many seconds each service is given to stop before it is killed.  Defaults to 10.
+func WithStopTimeout(seconds int) Option {
+	return func(s *settings) {
+		s.stopTimeout = seconds
+	}
+}
+
+// WithDiagnosticLines sets how many lines of output and monitored files are included in the report for a failed
+// service.  Defaults to 50.
+func WithDiagnosticLines(lines int) Option {
+	return func(s *settings) {
+		s.diagnosticLines = lines
+	}
+}
+
+// WithArtifactsDir writes the report for a failed service to dir
+func WithArtifactsDir(dir string) Option {
+	return func(s *settings) {
+		s.artifactsDir = dir
+	}
+}
+
+// WithJUnitReport writes a junit report of the startup results of each service to path once Up has finished
+func WithJUnitReport(path string) Option {
+	return func(s *settings) {
+		s.junitReport = path
+	}
+}
+
+// WithTimings reports how long each step of starting each service took once Up has finished
+func WithTimings(timings bool) Option {
+	return func(s *settings) {
+		s.timings = timings
+	}
+}
+
+// WithRemoveVolumes makes Down remove the volumes created for the project as well as its containers, and has the
+// cleanup done by WithAbortCleanup remove the volumes of the containers it removes
+func WithRemoveVolumes(removeVolumes bool) Option {
+	return func(s *settings) {
+		s.removeVolumes = removeVolumes
+	}
+}
+
+// WithRemoveExports makes Down remove the directories created to export monitored files
+func WithRemoveExports(removeExports bool) Option {
+	return func(s *settings) {
+		s.removeExports = removeExports
+	}
+}
+
+// WithReporter sends the events of Up to r.  By default they are written as text to our output.
+func WithReporter(r reporter.Reporter) Option {
+	return func(s *settings) {
+		s.reporter = r
+	}
+}
+
+// WithOutput sets where messages meant for people, such as progress messages from our handlers and failure reports,
+// are written.  Defaults to STDOUT.
+func WithOutput(out io.Writer) Option {
+	return func(s *settings) {
+		s.out = out
+	}
+}
+
+// WithDockerClient uses dockerClient for everything we ask of docker, including the containers libcompose creates
+// for us, rather than connecting with the default options.  A fakedocker.Client can be used to run without a daemon.
+func WithDockerClient(dockerClient client.APIClient) Option {
+	return func(s *settings) {
+		s.dockerClient = dockerClient
+	}
+}
@@ -0,0 +1,97 @@
+package compose
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dansteen/controlled-compose/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	dockerTypes "github.com/docker/engine-api/types"
+	"golang.org/x/net/context"
+)
+
+// ServiceStatus holds the state of the container of a single service
+type ServiceStatus struct {
+	Service   string
+	Container string
+	ID        string
+	// State is the state docker reports for the container (such as running or exited), or missing if the
+	// service does not have a container
+	State    string
+	ExitCode int
+	// Health is the status of the HEALTHCHECK of the container, if it has one
+	Health string
+}
+
+// Running returns true if the container of the service is running
+func (s ServiceStatus) Running() bool {
+	return s.State == "running"
+}
+
+// Status returns the state of the container of each of our services, in the order they are started in
+func (c *Composition) Status(ctx context.Context) ([]ServiceStatus, error) {
+	dockerClient, err := c.docker()
+	if err != nil {
+		return nil, err
+	}
+	orderedServices, err := c.project.SortedServices()
+	if err != nil {
+		return nil, &types.ConfigError{Err: err}
+	}
+
+	statuses := make([]ServiceStatus, 0, len(orderedServices))
+	for _, service_name := range orderedServices {
+		status := ServiceStatus{Service: service_name, State: "missing"}
+		containers, err := c.project.Containers(ctx, service_name)
+		if err != nil {
+			return nil, &types.DockerAPIError{Op: fmt.Sprintf("list the containers of %v", service_name), Err: err}
+		}
+		// We only spin up one for each services so we can just grab the first one
+		if len(containers) > 0 {
+			status.Container = containers[0].Name()
+			info, err := dockerClient.ContainerInspect(ctx, status.Container)
+			if err != nil {
+				return nil, &types.DockerAPIError{Op: fmt.Sprintf("inspect container %v", status.Container), Err: err}
+			}
+			status.ID = info.ContainerJSONBase.ID
+			status.State = info.ContainerJSONBase.State.Status
+			status.ExitCode = info.ContainerJSONBase.State.ExitCode
+			if info.ContainerJSONBase.State.Health != nil {
+				status.Health = info.ContainerJSONBase.State.Health.Status
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Logs copies everything the container of service has written so far to STDOUT and STDERR to stdout and stderr
+func (c *Composition) Logs(ctx context.Context, service string, stdout io.Writer, stderr io.Writer) error {
+	dockerClient, err := c.docker()
+	if err != nil {
+		return err
+	}
+	if _, found := c.project.Services[service]; !found {
+		return &types.ConfigError{Err: fmt.Errorf("Service %v is not included in the config", service)}
+	}
+	containers, err := c.project.Containers(ctx, service)
+	if err != nil {
+		return &types.DockerAPIError{Op: fmt.Sprintf("list the containers of %v", service), Err: err}
+	}
+	if len(containers) == 0 {
+		return &types.NoContainerError{Service: service}
+	}
+
+	logReadCloser, err := dockerClient.ContainerLogs(ctx, containers[0].Name(), dockerTypes.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       "all",
+	})
+	if err != nil {
+		return &types.DockerAPIError{Op: fmt.Sprintf("read the logs of %v", containers[0].Name()), Err: err}
+	}
+	defer logReadCloser.Close()
+	// docker sends both streams down the same connection, so we split them back out
+	_, err = stdcopy.StdCopy(stdout, stderr, logReadCloser)
+	return err
+}
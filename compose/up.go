@@ -0,0 +1,327 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dansteen/controlled-compose/control"
+	"github.com/dansteen/controlled-compose/handler"
+	"github.com/dansteen/controlled-compose/reporter"
+	"github.com/dansteen/controlled-compose/types"
+	"github.com/docker/engine-api/client"
+	"github.com/docker/libcompose/project/events"
+	"github.com/docker/libcompose/project/options"
+	"golang.org/x/net/context"
+)
+
+// Up starts our services.  Each service is started once all of its dependencies have succeeded, and an error is
+// returned if any of them fail or ctx is cancelled before they have all started.  If services are provided only they
+// and the services they depend on are started.  The rest of the project is still there for later calls.
+func (c *Composition) Up(ctx context.Context, services ...string) error {
+	// if we have been asked for a junit report or timings we collect results for those as well
+	runReporter := c.settings.reporter
+	var junitReporter *reporter.JUnitReporter
+	if c.settings.junitReport != "" {
+		junitReporter = reporter.NewJUnit(c.name)
+		runReporter = reporter.Multi{runReporter, junitReporter}
+	}
+	var timingReporter *reporter.TimingReporter
+	if c.settings.timings {
+		timingReporter = reporter.NewTiming()
+		runReporter = reporter.Multi{runReporter, timingReporter}
+	}
+
+	// if we were given services we only start them and what they depend on.  we work from a copy so later calls still
+	// see the whole project
+	project := c.project
+	if len(services) > 0 {
+		var err error
+		project, err = c.project.Select(services)
+		if err != nil {
+			return &types.ConfigError{Err: err}
+		}
+	}
+	orderedServices, err := project.SortedServices()
+	if err != nil {
+		return &types.ConfigError{Err: err}
+	}
+	runReporter.Report(reporter.Event{
+		Time:     time.Now(),
+		Type:     reporter.RunStarting,
+		Services: orderedServices,
+	})
+
+	// create a connection to the docker server
+	dockerClient, err := c.docker()
+	if err != nil {
+		return err
+	}
+
+	// run through and start up our services.  each service is started once all of its dependencies have succeeded
+	// we keep track of what we have started so we know what to clean up
+	var started []string
+	var startedLock sync.Mutex
+	err = project.Schedule(ctx, c.settings.parallelism, func(ctx context.Context, service_name string) error {
+		startedLock.Lock()
+		started = append(started, service_name)
+		startedLock.Unlock()
+		return c.upService(ctx, runReporter, dockerClient, service_name)
+	})
+	// if we were cancelled, that is what we report rather than whichever service happened to notice first
+	if err != nil && ctx.Err() != nil {
+		err = &types.InterruptedError{Err: ctx.Err()}
+	}
+	if _, ok := err.(*control.DependencyError); ok {
+		err = &types.ConfigError{Err: err}
+	}
+
+	if timingReporter != nil {
+		runReporter.Report(reporter.Event{
+			Time:    time.Now(),
+			Type:    reporter.TimingReport,
+			Timings: timingReporter.Summary(project.Dependencies()),
+		})
+	}
+	if err != nil {
+		runReporter.Report(reporter.Event{
+			Time:   time.Now(),
+			Type:   reporter.RunFinished,
+			Status: &types.ContainerStatus{Status: "failure", Message: fmt.Sprintf("Failed! - %v", err)},
+		})
+		c.writeJUnitReport(junitReporter)
+		if c.settings.abortCleanup {
+			c.cleanup(started)
+		}
+		return err
+	}
+	runReporter.Report(reporter.Event{
+		Time:   time.Now(),
+		Type:   reporter.RunFinished,
+		Status: &types.ContainerStatus{Status: "success", Message: "All services started successfully"},
+	})
+	c.writeJUnitReport(junitReporter)
+	return nil
+}
+
+// writeJUnitReport writes out our junit report if we were asked for one
+func (c *Composition) writeJUnitReport(junitReporter *reporter.JUnitReporter) {
+	if junitReporter == nil {
+		return
+	}
+	err := junitReporter.Write(c.settings.junitReport)
+	if err != nil {
+		fmt.Fprintf(c.settings.out, "Could not write junit report to %v: %v\n", c.settings.junitReport, err)
+	}
+}
+
+// cleanup stops and removes the services that were started, in the reverse of the order they were started in.  Their
+// volumes are only removed if we were asked to with WithRemoveVolumes.  We may be cleaning up because our context was
+// cancelled, so we do not use it here.
+func (c *Composition) cleanup(started []string) {
+	reversed := make([]string, 0, len(started))
+	for index := len(started) - 1; index >= 0; index-- {
+		reversed = append(reversed, started[index])
+	}
+	fmt.Fprintf(c.settings.out, "Cleaning up services: %v\n", reversed)
+	err := c.project.StopServices(context.Background(), c.settings.out, reversed, c.settings.stopTimeout, c.settings.removeVolumes)
+	if err != nil {
+		fmt.Fprintf(c.settings.out, "Failed to clean up - %v\n", err)
+	}
+}
+
+// upService starts a single service and waits for its state conditions to be decided.  If the conditions fail in a
+// way that its retry policy allows, the service is recreated and monitored again.  An error is returned if the
+// service did not succeed.
+func (c *Composition) upService(ctx context.Context, runReporter reporter.Reporter, dockerClient client.APIClient, service_name string) error {
+	project := c.project
+	// any failure is reported along with whatever we know about the container at the time
+	var container_id string
+	fail := func(err error, status *types.ContainerStatus, details string) error {
+		runReporter.Report(reporter.Event{
+			Time:      time.Now(),
+			Type:      reporter.ServiceFailed,
+			Service:   service_name,
+			Container: container_id,
+			Status:    status,
+			Message:   err.Error(),
+			Details:   details,
+		})
+		return err
+	}
+
+	runReporter.Report(reporter.Event{
+		Time:    time.Now(),
+		Type:    reporter.ServiceStarting,
+		Service: service_name,
+	})
+
+	conditions, hasConditions := project.StateConditions[service_name]
+	retry := conditions.Retry
+	if retry == nil {
+		retry = &types.Retry{Attempts: 1}
+	}
+	backoff := retry.Backoff
+	for attempt := 1; ; attempt++ {
+		// we always recreate our container so each attempt starts from scratch
+		c.composeLock.Lock()
+		err := project.ComposeProject.Create(ctx, options.Create{ForceRecreate: true}, service_name)
+		c.composeLock.Unlock()
+		if err != nil {
+			return fail(&types.DockerAPIError{Op: fmt.Sprintf("create service %v", service_name), Err: err}, nil, "")
+		}
+
+		// get the container name for this service.
+		c.composeLock.Lock()
+		containers, err := project.Containers(ctx, service_name)
+		c.composeLock.Unlock()
+		if err != nil {
+			return fail(&types.DockerAPIError{Op: fmt.Sprintf("list the containers of %v", service_name), Err: err}, nil, "")
+		}
+		// We only spin up one for each services so we can just grab the first one
+		if len(containers) == 0 {
+			return fail(&types.NoContainerError{Service: service_name}, nil, "")
+		}
+		container_name := containers[0].Name()
+		container_id, err = containers[0].ID()
+		if err != nil {
+			return fail(&types.DockerAPIError{Op: fmt.Sprintf("get the id of %v", container_name), Err: err}, nil, "")
+		}
+		runReporter.Report(reporter.Event{
+			Time:      time.Now(),
+			Type:      reporter.ContainerCreated,
+			Service:   service_name,
+			Container: container_id,
+			Message:   container_name,
+		})
+		c.composeLock.Lock()
+		err = project.ComposeProject.Start(ctx, service_name)
+		c.composeLock.Unlock()
+		if err != nil {
+			return fail(&types.DockerAPIError{Op: fmt.Sprintf("start service %v", service_name), Err: err}, nil, "")
+		}
+		runReporter.Report(reporter.Event{
+			Time:      time.Now(),
+			Type:      reporter.ContainerStarted,
+			Service:   service_name,
+			Container: container_id,
+		})
+
+		// without any state conditions there is nothing to wait on
+		if !hasConditions {
+			break
+		}
+
+		// our condition engine starts the handlers for each of the conditions and works out the result
+		event_response := make(chan types.ContainerStatus)
+		monitor, stopMonitoring := context.WithCancel(ctx)
+		container := handler.Container{
+			Client: dockerClient,
+			Name:   container_name,
+			Events: func(ctx context.Context) (<-chan events.ContainerEvent, error) {
+				c.composeLock.Lock()
+				defer c.composeLock.Unlock()
+				return project.ComposeProject.Events(ctx, service_name)
+			},
+			Out: c.settings.out,
+		}
+		go handler.Evaluate(monitor, container, conditions, event_response)
+
+		// wait until we have been given the go-ahead to move on to the next service if we need to
+		var response types.ContainerStatus
+		select {
+		case response = <-event_response:
+		case <-ctx.Done():
+		}
+		// we have to be sure to stop our monitors as some of them may still be running
+		stopMonitoring()
+		if ctx.Err() != nil {
+			return fail(fmt.Errorf("Stopped waiting for %v: %v", container_name, ctx.Err()), nil, "")
+		}
+		// if a handler could not do its job we do not know whether the service is healthy, so there is no point in
+		// retrying it.  we still want to know what the container was up to at the time
+		if response.Status == "error" {
+			diagnostics := c.diagnose(dockerClient, service_name, container_name, conditions, response)
+			return fail(&types.ConditionError{
+				Service:   service_name,
+				Container: container_name,
+				Status:    response,
+				Err:       response.Err,
+				Report:    diagnostics.String(),
+			}, &response, diagnostics.String())
+		}
+		eventType := reporter.ConditionMatched
+		if strings.HasSuffix(response.Condition, "timeout") {
+			eventType = reporter.ConditionTimedOut
+		}
+		runReporter.Report(reporter.Event{
+			Time:      time.Now(),
+			Type:      eventType,
+			Service:   service_name,
+			Container: container_id,
+			Status:    &response,
+		})
+		if response.Status == "success" {
+			runReporter.Report(reporter.Event{
+				Time:      time.Now(),
+				Type:      reporter.ServiceSucceeded,
+				Service:   service_name,
+				Container: container_id,
+				Status:    &response,
+			})
+			return nil
+		}
+
+		// see if we are allowed to try again
+		if attempt < retry.Attempts && retry.Retryable(response.Condition) {
+			runReporter.Report(reporter.Event{
+				Time:      time.Now(),
+				Type:      reporter.ServiceRetrying,
+				Service:   service_name,
+				Container: container_id,
+				Status:    &response,
+				Message:   fmt.Sprintf("attempt %v of %v failed, retrying in %v", attempt, retry.Attempts, backoff),
+			})
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return fail(fmt.Errorf("Stopped waiting to retry %v: %v", container_name, ctx.Err()), nil, "")
+			}
+			backoff *= 2
+			continue
+		}
+
+		// let people know what went wrong
+		diagnostics := c.diagnose(dockerClient, service_name, container_name, conditions, response)
+		err = &types.ConditionFailedError{
+			Service:   service_name,
+			Container: container_name,
+			Status:    response,
+			Attempts:  attempt,
+			Report:    diagnostics.String(),
+		}
+		return fail(err, &response, diagnostics.String())
+	}
+	runReporter.Report(reporter.Event{
+		Time:      time.Now(),
+		Type:      reporter.ServiceSucceeded,
+		Service:   service_name,
+		Container: container_id,
+	})
+	return nil
+}
+
+// diagnose collects the failure report for a service whose conditions did not succeed, prints it, and saves it to our
+// artifacts directory if we have one
+func (c *Composition) diagnose(dockerClient client.APIClient, service_name string, container_name string, conditions types.StateConditions, response types.ContainerStatus) control.Diagnostics {
+	diagnostics := control.Diagnose(dockerClient, service_name, container_name, conditions, response, c.settings.diagnosticLines)
+	fmt.Fprint(c.settings.out, diagnostics)
+	if c.settings.artifactsDir != "" {
+		err := diagnostics.Write(c.settings.artifactsDir)
+		if err != nil {
+			fmt.Fprintf(c.settings.out, "Could not write failure report to %v: %v\n", c.settings.artifactsDir, err)
+		}
+	}
+	return diagnostics
+}
@@ -0,0 +1,240 @@
+package compose_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/dansteen/controlled-compose/compose"
+	"github.com/dansteen/controlled-compose/fakedocker"
+	"github.com/dansteen/controlled-compose/types"
+	dockerTypes "github.com/docker/engine-api/types"
+	"golang.org/x/net/context"
+)
+
+// upScenario is a project being brought up while its containers play out a scenario
+type upScenario struct {
+	name string
+	// config is the compose file of the project
+	config string
+	// parallelism is passed to WithParallelism, where 0 means no limit, and removeVolumes to WithRemoveVolumes
+	parallelism   int
+	removeVolumes bool
+	// exits holds the code each container of a service exits with, one for each attempt to start the service.
+	// Containers of services that are not listed keep running
+	exits map[string][]int
+	// interrupt cancels Up once the container of the service is being monitored
+	interrupt string
+	// check looks at what Up returned
+	check func(t *testing.T, err error)
+	// how many containers we expect to have been started for each service, and how many containers are left once Up
+	// has returned
+	attempts  map[string]int
+	remaining int
+	// volumes is how many containers were removed along with their volumes
+	volumes int
+}
+
+var upScenarios = []upScenario{
+	{
+		name: "retry then succeed",
+		config: `version: '2'
+services:
+  db.local:
+    image: postgres:9.3
+    state_conditions:
+      exit: [0]
+      retry:
+        attempts: 3
+        backoff: 0.01
+        on: [exit]
+`,
+		exits: map[string][]int{"db.local": {1, 0}},
+		check: func(t *testing.T, err error) {
+			if err != nil {
+				t.Errorf("expected the second attempt to succeed but got %v", err)
+			}
+		},
+		attempts:  map[string]int{"db.local": 2},
+		remaining: 1,
+	},
+	{
+		name: "retries run out and we clean up",
+		config: `version: '2'
+services:
+  db.local:
+    image: postgres:9.3
+    state_conditions:
+      exit: [0]
+      retry:
+        attempts: 2
+        backoff: 0.01
+        on: [exit]
+  web.local:
+    image: nginx
+    depends_on:
+      - db.local
+`,
+		removeVolumes: true,
+		exits:         map[string][]int{"db.local": {1, 1}},
+		check: func(t *testing.T, err error) {
+			failed, ok := err.(*types.ConditionFailedError)
+			if !ok {
+				t.Fatalf("expected a *types.ConditionFailedError but got %T: %v", err, err)
+			}
+			if failed.Service != "db.local" || failed.Attempts != 2 {
+				t.Errorf("expected db.local to fail after 2 attempts but %v failed after %v", failed.Service, failed.Attempts)
+			}
+		},
+		attempts:  map[string]int{"db.local": 2},
+		remaining: 0,
+		// the first container is replaced by the retry, which keeps its volumes
+		volumes: 1,
+	},
+	{
+		name: "services that do not depend on each other start in parallel",
+		config: `version: '2'
+services:
+  db.local:
+    image: postgres:9.3
+    state_conditions:
+      exit: [0]
+  cache.local:
+    image: redis
+    state_conditions:
+      exit: [0]
+  queue.local:
+    image: rabbitmq
+    state_conditions:
+      exit: [0]
+  web.local:
+    image: nginx
+    depends_on:
+      - db.local
+      - cache.local
+      - queue.local
+`,
+		parallelism: 3,
+		exits:       map[string][]int{"db.local": {0}, "cache.local": {0}, "queue.local": {0}},
+		check: func(t *testing.T, err error) {
+			if err != nil {
+				t.Errorf("expected every service to start but got %v", err)
+			}
+		},
+		attempts:  map[string]int{"db.local": 1, "cache.local": 1, "queue.local": 1, "web.local": 1},
+		remaining: 4,
+	},
+	{
+		name: "interrupt",
+		config: `version: '2'
+services:
+  db.local:
+    image: postgres:9.3
+    state_conditions:
+      exit: [-1]
+      timeout:
+        duration: 60
+        status: failure
+  web.local:
+    image: nginx
+    depends_on:
+      - db.local
+`,
+		interrupt: "db.local",
+		check: func(t *testing.T, err error) {
+			if _, ok := err.(*types.InterruptedError); !ok {
+				t.Errorf("expected a *types.InterruptedError but got %T: %v", err, err)
+			}
+		},
+		attempts:  map[string]int{"db.local": 1},
+		remaining: 0,
+	},
+}
+
+func TestUp(t *testing.T) {
+	for _, test := range upScenarios {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "docker-compose.yml")
+			if err := ioutil.WriteFile(file, []byte(test.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// we count the containers started for each service, and play out what they do as they are started
+			fake := fakedocker.New()
+			var lock sync.Mutex
+			attempts := make(map[string]int)
+			fake.OnStart(func(name string) {
+				info, err := fake.ContainerInspect(ctx, name)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				service := info.Config.Labels["com.docker.compose.service"]
+				lock.Lock()
+				attempts[service]++
+				attempt := attempts[service]
+				lock.Unlock()
+
+				// events only go to the handlers that are listening at the time, so we wait for them
+				codes := test.exits[service]
+				if service != test.interrupt && attempt > len(codes) {
+					return
+				}
+				if fake.WaitForListener(ctx, name) != nil {
+					return
+				}
+				if service == test.interrupt {
+					cancel()
+					return
+				}
+				fake.Exit(name, codes[attempt-1])
+			})
+
+			composition, err := compose.New("uptest", []string{file},
+				compose.WithDockerClient(fake),
+				compose.WithAbortCleanup(true),
+				compose.WithParallelism(test.parallelism),
+				compose.WithRemoveVolumes(test.removeVolumes),
+				compose.WithOutput(ioutil.Discard),
+			)
+			if err != nil {
+				t.Fatalf("could not read the project: %v", err)
+			}
+			test.check(t, composition.Up(ctx))
+
+			lock.Lock()
+			defer lock.Unlock()
+			for service, expected := range test.attempts {
+				if attempts[service] != expected {
+					t.Errorf("expected %v to be started %v times but it was started %v times", service, expected, attempts[service])
+				}
+			}
+			for service, count := range attempts {
+				if _, found := test.attempts[service]; !found {
+					t.Errorf("expected %v not to be started but it was started %v times", service, count)
+				}
+			}
+			containers, err := fake.ContainerList(context.Background(), dockerTypes.ContainerListOptions{All: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(containers) != test.remaining {
+				t.Errorf("expected %v containers to be left but found %v", test.remaining, len(containers))
+			}
+			volumes := 0
+			for _, removeVolumes := range fake.Removed() {
+				if removeVolumes {
+					volumes++
+				}
+			}
+			if volumes != test.volumes {
+				t.Errorf("expected %v containers to be removed along with their volumes but %v were", test.volumes, volumes)
+			}
+		})
+	}
+}
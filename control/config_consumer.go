@@ -5,7 +5,7 @@ import (
 	"github.com/dansteen/controlled-compose/types"
 	"github.com/docker/libcompose/config"
 	"github.com/docker/libcompose/utils"
-	"github.com/imdario/mergo.git"
+	"github.com/imdario/mergo"
 	"io/ioutil"
 	"path/filepath"
 )
@@ -49,36 +49,60 @@ func processRequires(file string, configFiles []string) ([]string, error) {
 }
 
 // consumeConfig reads in config files, merges the services sections in the order the files are provided (or required), and returns a single byte array
-func consumeConfigs(files []string) ([]byte, error) {
+// along with a map of each service to the files it was defined in
+func consumeConfigs(files []string) ([]byte, map[string][]string, error) {
 	// variables to hold our config components
 	//var services config.RawServiceMap
 	//volumes := make(map[string]*config.VolumeConfig, 0)
 	//networks := make(map[string]*config.NetworkConfig, 0)
 	//var version string
 
-	var configContent config.Config
 	var mergedConfig config.Config
+	serviceFiles := make(map[string][]string)
 	for _, file := range files {
+		// we start fresh for each file so we know which services it defines
+		var configContent config.Config
 		// read in our config
 		content, err := ioutil.ReadFile(file)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		// parse the content
 		err = yaml.Unmarshal(content, &configContent)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		// keep track of where each service came from
+		for name := range configContent.Services {
+			serviceFiles[name] = append(serviceFiles[name], file)
 		}
 		// add the content to our existing set
 		err = mergo.Merge(&mergedConfig, configContent)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 	yamlConfig, err := yaml.Marshal(mergedConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return []byte(yamlConfig), serviceFiles, nil
+}
+
+// ResolvedConfig returns our merged config as yaml, with the services as they were after processConfig had
+// rewritten image versions and exported monitored folders
+func (p *Project) ResolvedConfig() ([]byte, error) {
+	var resolvedConfig config.Config
+	err := yaml.Unmarshal(p.mergedConfig, &resolvedConfig)
 	if err != nil {
 		return nil, err
 	}
+	resolvedConfig.Services = p.resolvedServices
 
+	yamlConfig, err := yaml.Marshal(resolvedConfig)
+	if err != nil {
+		return nil, err
+	}
 	return []byte(yamlConfig), nil
 }
@@ -2,13 +2,15 @@ package control
 
 import (
 	"fmt"
+	"github.com/dansteen/controlled-compose/handler"
 	"github.com/dansteen/controlled-compose/types"
 	"github.com/docker/libcompose/config"
-	"github.com/imdario/mergo.git"
+	"github.com/docker/libcompose/project"
+	"github.com/imdario/mergo"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"time"
 )
 
 // processConfig handles our config preprocessing.  We need to save off the parts of the config that we created
@@ -17,9 +19,13 @@ func (p *Project) processConfig(services config.RawServiceMap) (config.RawServic
 	for name, config := range services {
 
 		// see if we need to swap out the version of this container
-		if image, found := config["image"]; found {
+		if imageRaw, found := config["image"]; found {
+			image, ok := imageRaw.(string)
+			if !ok {
+				return nil, &handler.FieldError{Service: name, Field: "image", Message: fmt.Sprintf("expected a string but found %v", handler.Describe(imageRaw))}
+			}
 			// strip off the version and repo ( if there is one)
-			imageParts := strings.Split(image.(string), ":")
+			imageParts := strings.Split(image, ":")
 			imageParts = strings.Split(imageParts[0], "/")
 			imageName := imageParts[len(imageParts)-1]
 			// see if the name matches
@@ -38,95 +44,32 @@ func (p *Project) processConfig(services config.RawServiceMap) (config.RawServic
 		var serviceName string
 		// see if this service extends another. if so, apply the state_conditions to that other service
 		if extendsService, found := config["extends"]; found {
-			extendedService := extendsService.(map[interface{}]interface{})
-			serviceName = extendedService["service"].(string)
+			extendedService, err := handler.NewFields(name, "extends", extendsService)
+			if err != nil {
+				return nil, err
+			}
+			err = extendedService.String("service", &serviceName)
+			if err != nil {
+				return nil, err
+			}
+			if serviceName == "" {
+				return nil, extendedService.Fail("service", "missing required key")
+			}
 		} else {
 			serviceName = name
 		}
 
 		// see if we have any state conditions applied
 		if configState, found := config["state_conditions"]; found {
-			configStateConditions := configState.(map[interface{}]interface{})
-			// collect our exit conditions
-			conditions := types.StateConditions{}
-
-			// look for exit codes
-			if codes, ok := configStateConditions["exit"]; ok {
-				// this is an array, but since it's formed as an interface we need to iterate and convert
-				codesInt := make([]int, 0)
-				for _, val := range codes.([]interface{}) {
-					codesInt = append(codesInt, int(val.(int64)))
-				}
-				conditions.ExitCodes = &types.ExitCodes{Codes: codesInt}
-			}
-
-			// look for log monitors
-			if _, ok := configStateConditions["filemonitor"]; ok {
-				conditions.FileMonitors = make(map[string][]types.FileMonitor)
-				for _, monitorRaw := range configStateConditions["filemonitor"].([]interface{}) {
-					monitor := monitorRaw.(map[interface{}]interface{})
-					// make sure our regex is valid
-					regex, err := regexp.Compile(monitor["regex"].(string))
-					if err != nil {
-						return nil, err
-					}
-
-					// we need to make sure that any folders that are being monitored are exported, so we add any missing ones
-					// we only do this if we are not monitoring STDOUT
-					if monitor["file"] != "STDOUT" && monitor["file"] != "STDERR" {
-						// first we get the directory of the log we are monitoring
-						dir := filepath.Dir(monitor["file"].(string))
-						found := false
-						// then we check if there are any volumes exported
-						if _, found := services[serviceName]["volumes"]; found {
-							// then we check to see if our folder is present in the already exported folders for this service
-							for _, val := range services[serviceName]["volumes"].([]interface{}) {
-								volume := val.(string)
-								// break out the parts
-								parts := strings.FieldsFunc(volume, func(c rune) bool { return c == ':' })
-								if parts[1] == dir {
-									found = true
-									break
-								}
-							}
-							// if no volumes were exported, we need to create the structures
-						} else {
-							services[serviceName]["volumes"] = make([]interface{}, 0)
-						}
-
-						// if we have not found it, then we add it in.  directories exported by this are named in the following fashion:
-						// <current_dir>/controlled_compose_<pid>/<service_name>
-						if !found {
-							// build our export dir
-							currDir, _ := os.Getwd()
-							exportDir := filepath.Join(currDir, fmt.Sprintf("controlled_compose_%v", os.Getpid()), serviceName)
-							// add in our volume
-							services[serviceName]["volumes"] = append(services[serviceName]["volumes"].([]interface{}), fmt.Sprintf("%v:%v", dir, exportDir))
-						}
-					}
-
-					// if we have not yet seen this filename we create a new storage array
-					filename := monitor["file"].(string)
-					if _, found := conditions.FileMonitors[filename]; !found {
-						conditions.FileMonitors[filename] = make([]types.FileMonitor, 0)
-					}
-					conditions.FileMonitors[filename] = append(conditions.FileMonitors[filename], types.FileMonitor{
-						File:   filename,
-						Regex:  regex,
-						Status: monitor["status"].(string),
-					})
-				}
+			configStateConditions, err := handler.NewFields(serviceName, "state_conditions", configState)
+			if err != nil {
+				return nil, err
 			}
-			// look for timeout
-			if timeout, ok := configStateConditions["timeout"]; ok {
-				timeout := timeout.(map[interface{}]interface{})
-				conditions.Timeout = &types.Timeout{
-					// TODO: technically we should be able to accept partial seconds here, but I have to figure out how to do arbitrary
-					// conversion from int64 to float64 based on what is read in by the yaml parser. So for now we only accept whole seconds
-					Duration: float64(timeout["duration"].(int64)),
-					Status:   timeout["status"].(string),
-				}
+			conditions, err := p.parseStateConditions(serviceName, configStateConditions, services)
+			if err != nil {
+				return nil, err
 			}
+
 			// add the conditions we found to our list.  merge them if we have already set one
 			if existingConditions, found := p.StateConditions[serviceName]; found {
 				// if we are setting this via extends, the values take precedence
@@ -143,5 +86,161 @@ func (p *Project) processConfig(services config.RawServiceMap) (config.RawServic
 			}
 		}
 	}
+	// save off the final version of our services so we can show people what we resolved
+	p.resolvedServices = services
 	return services, nil
 }
+
+// parseStateConditions decodes a state_conditions stanza for serviceName into our StateConditions.  Groups of
+// conditions (all_of, any_of and sequence) are decoded recursively.
+func (p *Project) parseStateConditions(serviceName string, configStateConditions handler.Fields, services config.RawServiceMap) (types.StateConditions, error) {
+	// collect our exit conditions
+	conditions := types.StateConditions{
+		Conditions: make(map[string]types.Condition),
+	}
+
+	// every kind of condition is registered with our handlers
+	for _, key := range handler.RegisteredConditions() {
+		if !configStateConditions.Has(key) {
+			continue
+		}
+		parse, _ := handler.LookupCondition(key)
+		condition, err := parse(handler.ParseContext{
+			Service: serviceName,
+			Path:    configStateConditions.Field(key),
+			ExportDir: func(dir string) (string, error) {
+				return p.exportMonitoredDir(serviceName, dir, services)
+			},
+		}, configStateConditions.Value(key))
+		if err != nil {
+			return conditions, err
+		}
+		if condition != nil {
+			conditions.Conditions[key] = condition
+		}
+	}
+
+	// look for a retry policy.  we retry by recreating the whole service, so this only makes sense for the service as
+	// a whole rather than a group inside of it
+	retryConfig, found, err := configStateConditions.Map("retry")
+	if err != nil {
+		return conditions, err
+	}
+	if found {
+		if configStateConditions.Path() != "state_conditions" {
+			return conditions, configStateConditions.Fail("retry", "retry can only be set directly under state_conditions")
+		}
+		conditions.Retry, err = parseRetry(retryConfig)
+		if err != nil {
+			return conditions, err
+		}
+	}
+
+	// look for groups of conditions
+	for _, group := range []string{"all_of", "any_of", "sequence"} {
+		members, found, err := configStateConditions.List(group)
+		if err != nil {
+			return conditions, err
+		}
+		if !found {
+			continue
+		}
+		groupConditions := make([]types.StateConditions, 0)
+		for index, memberRaw := range members {
+			memberConfig, err := handler.NewFields(serviceName, fmt.Sprintf("%v.%v[%v]", configStateConditions.Path(), group, index), memberRaw)
+			if err != nil {
+				return conditions, err
+			}
+			member, err := p.parseStateConditions(serviceName, memberConfig, services)
+			if err != nil {
+				return conditions, err
+			}
+			groupConditions = append(groupConditions, member)
+		}
+		switch group {
+		case "all_of":
+			conditions.AllOf = groupConditions
+		case "any_of":
+			conditions.AnyOf = groupConditions
+		case "sequence":
+			conditions.Sequence = groupConditions
+		}
+	}
+	return conditions, configStateConditions.Unknown()
+}
+
+// parseRetry decodes a retry policy
+func parseRetry(retryConfig handler.Fields) (*types.Retry, error) {
+	retry := &types.Retry{
+		Attempts: 3,
+		Backoff:  time.Second,
+		On:       []string{"exit", "timeout", "regex", "probe"},
+	}
+	for _, err := range []error{
+		retryConfig.Int("attempts", &retry.Attempts),
+		retryConfig.Duration("backoff", &retry.Backoff),
+		retryConfig.Strings("on", &retry.On),
+		retryConfig.Unknown(),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	if retry.Attempts < 1 {
+		return nil, retryConfig.Fail("attempts", "expected at least 1 attempt")
+	}
+	for index, kind := range retry.On {
+		if kind != "exit" && kind != "timeout" && kind != "regex" && kind != "probe" {
+			return nil, retryConfig.Fail(fmt.Sprintf("on[%v]", index), "expected one of exit, timeout, regex or probe but found %#v", kind)
+		}
+	}
+	return retry, nil
+}
+
+// exportMonitoredDir makes sure that dir, a directory inside the container for serviceName, is exported as a volume
+// so we can monitor files in it, and returns the directory on the host it is exported to.  If it is not already
+// exported we add a volume for it.
+func (p *Project) exportMonitoredDir(serviceName string, dir string, services config.RawServiceMap) (string, error) {
+	service, found := services[serviceName]
+	if !found {
+		return "", &handler.FieldError{Service: serviceName, Field: "state_conditions.filemonitor", Message: "monitored files can only be used on services that are defined"}
+	}
+
+	// then we check if there are any volumes exported
+	volumes := make([]interface{}, 0)
+	if volumesRaw, found := service["volumes"]; found {
+		var ok bool
+		volumes, ok = volumesRaw.([]interface{})
+		if !ok {
+			return "", &handler.FieldError{Service: serviceName, Field: "volumes", Message: fmt.Sprintf("expected a list but found %v", handler.Describe(volumesRaw))}
+		}
+	}
+
+	// then we check to see if our folder is present in the already exported folders for this service
+	for index, val := range volumes {
+		volume, ok := val.(string)
+		if !ok {
+			return "", &handler.FieldError{Service: serviceName, Field: fmt.Sprintf("volumes[%v]", index), Message: fmt.Sprintf("expected a string but found %v", handler.Describe(val))}
+		}
+		// break out the parts.  volumes are given as HOST:CONTAINER[:MODE]
+		parts := strings.FieldsFunc(volume, func(c rune) bool { return c == ':' })
+		if len(parts) > 1 && parts[1] == dir {
+			// docker keeps named volumes to itself, so we have no way to read them
+			if project.IsNamedVolume(parts[0]) {
+				return "", &handler.FieldError{Service: serviceName, Field: fmt.Sprintf("volumes[%v]", index), Message: fmt.Sprintf("%v is monitored so it has to be exported to a directory rather than the named volume %v", dir, parts[0])}
+			}
+			// relative paths are resolved against our working directory, the same as libcompose does for us
+			return filepath.Abs(parts[0])
+		}
+	}
+
+	// if we have not found it, then we add it in.  directories exported by this are named in the following fashion:
+	// <current_dir>/controlled_compose_<pid>/<service_name>
+	currDir, _ := os.Getwd()
+	exportDir := filepath.Join(currDir, fmt.Sprintf("controlled_compose_%v", os.Getpid()), serviceName)
+	// add in our volume, and remember that we did so we can tell people about it
+	volume := fmt.Sprintf("%v:%v", exportDir, dir)
+	service["volumes"] = append(volumes, volume)
+	p.ExportedVolumes[serviceName] = append(p.ExportedVolumes[serviceName], volume)
+	return exportDir, nil
+}
@@ -0,0 +1,187 @@
+package control_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dansteen/controlled-compose/control"
+	"github.com/dansteen/controlled-compose/fakedocker"
+	"github.com/dansteen/controlled-compose/handler"
+	"github.com/dansteen/controlled-compose/types"
+)
+
+var retryTests = []struct {
+	name  string
+	retry string
+	want  types.Retry
+}{
+	{
+		name:  "defaults",
+		retry: "{}",
+		want:  types.Retry{Attempts: 3, Backoff: time.Second, On: []string{"exit", "timeout", "regex", "probe"}},
+	},
+	{
+		// yaml reads an unquoted on as true
+		name:  "an unquoted on",
+		retry: "{attempts: 2, backoff: 1m30s, on: [exit]}",
+		want:  types.Retry{Attempts: 2, Backoff: 90 * time.Second, On: []string{"exit"}},
+	},
+	{
+		name:  "a quoted on",
+		retry: "{backoff: 0.5, 'on': [timeout, probe]}",
+		want:  types.Retry{Attempts: 3, Backoff: 500 * time.Millisecond, On: []string{"timeout", "probe"}},
+	},
+}
+
+func TestRetry(t *testing.T) {
+	for _, test := range retryTests {
+		t.Run(test.name, func(t *testing.T) {
+			project := genProject(t, `version: '2'
+services:
+  db:
+    image: postgres
+    state_conditions:
+      exit: [0]
+      retry: `+test.retry+`
+`)
+			retry := project.StateConditions["db"].Retry
+			if retry == nil {
+				t.Fatalf("expected a retry policy but there was none")
+			}
+			if !reflect.DeepEqual(*retry, test.want) {
+				t.Errorf("expected %+v but got %+v", test.want, *retry)
+			}
+		})
+	}
+}
+
+var unknownKeyTests = []struct {
+	name       string
+	conditions string
+	field      string
+}{
+	{
+		name:       "a misspelled condition",
+		conditions: "{exit: [0], timout: {duration: 5, status: failure}}",
+		field:      "state_conditions.timout",
+	},
+	{
+		name:       "a misspelled condition in a group",
+		conditions: "{any_of: [{exit: [0]}, {htp: {port: 80}}]}",
+		field:      "state_conditions.any_of[1].htp",
+	},
+	{
+		name:       "a misspelled retry key",
+		conditions: "{exit: [0], retry: {attempts: 2, bakoff: 5}}",
+		field:      "state_conditions.retry.bakoff",
+	},
+}
+
+func TestUnknownKeys(t *testing.T) {
+	for _, test := range unknownKeyTests {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "docker-compose.yml")
+			config := "version: '2'\nservices:\n  db:\n    image: postgres\n    state_conditions: " + test.conditions + "\n"
+			if err := ioutil.WriteFile(file, []byte(config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := control.GenProject("controltest", []string{file}, nil, fakedocker.New())
+			fieldErr, ok := err.(*handler.FieldError)
+			if !ok {
+				t.Fatalf("expected a *handler.FieldError for %v but got %T: %v", test.field, err, err)
+			}
+			if fieldErr.Field != test.field {
+				t.Errorf("expected %v to be reported but got %v", test.field, fieldErr)
+			}
+		})
+	}
+}
+
+var exportTests = []struct {
+	name    string
+	volumes string
+	// where we expect the monitored file to be read from on the host, and the volumes we expect to have added, both
+	// relative to our working directory
+	monitored string
+	exported  []string
+	// the field we expect to be reported if the file can not be monitored
+	field string
+}{
+	{
+		name:      "a directory that is not exported is exported for the run",
+		monitored: "controlled_compose_<pid>/db/app.log",
+		exported:  []string{"controlled_compose_<pid>/db:/var/log/app"},
+	},
+	{
+		name:      "a directory exported to an absolute path is read from there",
+		volumes:   "['/srv/logs:/var/log/app']",
+		monitored: "/srv/logs/app.log",
+	},
+	{
+		name:      "a directory exported to a relative path is read from our working directory",
+		volumes:   "['./logs:/var/log/app:ro']",
+		monitored: "logs/app.log",
+	},
+	{
+		name:    "a directory exported to a named volume can not be read",
+		volumes: "['logs:/var/log/app']",
+		field:   "volumes[0]",
+	},
+}
+
+func TestExport(t *testing.T) {
+	currDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// expand fills in the pid of our run, and makes path absolute
+	expand := func(path string) string {
+		path = strings.Replace(path, "<pid>", strconv.Itoa(os.Getpid()), -1)
+		if filepath.IsAbs(path) || path == "" {
+			return path
+		}
+		return filepath.Join(currDir, path)
+	}
+	for _, test := range exportTests {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "docker-compose.yml")
+			config := "version: '2'\nservices:\n  db:\n    image: postgres\n"
+			if test.volumes != "" {
+				config += "    volumes: " + test.volumes + "\n"
+			}
+			config += "    state_conditions: {filemonitor: [{file: /var/log/app/app.log, regex: ready, status: success}]}\n"
+			if err := ioutil.WriteFile(file, []byte(config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			project, err := control.GenProject("controltest", []string{file}, nil, fakedocker.New())
+			if test.field != "" {
+				fieldErr, ok := err.(*handler.FieldError)
+				if !ok || fieldErr.Field != test.field {
+					t.Errorf("expected %v to be reported but got %v", test.field, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			watcher := project.StateConditions["db"].Conditions["filemonitor"].(handler.FileWatcher)
+			if monitored := watcher.MonitoredFiles(); !reflect.DeepEqual(monitored, []string{expand(test.monitored)}) {
+				t.Errorf("expected %v to be monitored but %v was", expand(test.monitored), monitored)
+			}
+			var exported []string
+			for _, volume := range test.exported {
+				exported = append(exported, expand(volume))
+			}
+			if !reflect.DeepEqual(project.ExportedVolumes["db"], exported) {
+				t.Errorf("expected %v to be exported but got %v", exported, project.ExportedVolumes["db"])
+			}
+		})
+	}
+}
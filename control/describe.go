@@ -0,0 +1,49 @@
+package control
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dansteen/controlled-compose/types"
+)
+
+// DescribeConditions formats parsed state conditions as readable lines, each one starting with indent
+func DescribeConditions(conditions types.StateConditions, indent string) string {
+	var description bytes.Buffer
+	// our registered conditions describe themselves.  we sort them so the output is stable
+	keys := make([]string, 0, len(conditions.Conditions))
+	for key := range conditions.Conditions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&description, "%v%v: %v\n", indent, key, conditions.Conditions[key].Describe())
+	}
+	if conditions.Retry != nil {
+		retry := conditions.Retry
+		fmt.Fprintf(&description, "%vretry: up to %v attempts on %v failures, waiting %v and doubling between attempts\n", indent, retry.Attempts, strings.Join(retry.On, ", "), retry.Backoff)
+	}
+
+	// and then our groups
+	groups := []struct {
+		name    string
+		members []types.StateConditions
+	}{
+		{"all_of", conditions.AllOf},
+		{"any_of", conditions.AnyOf},
+		{"sequence", conditions.Sequence},
+	}
+	for _, group := range groups {
+		if len(group.members) == 0 {
+			continue
+		}
+		fmt.Fprintf(&description, "%v%v:\n", indent, group.name)
+		for index, member := range group.members {
+			fmt.Fprintf(&description, "%v  - %v:\n", indent, index+1)
+			description.WriteString(DescribeConditions(member, indent+"      "))
+		}
+	}
+	return description.String()
+}
@@ -0,0 +1,163 @@
+package control
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dansteen/controlled-compose/handler"
+	"github.com/dansteen/controlled-compose/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/engine-api/client"
+	dockerTypes "github.com/docker/engine-api/types"
+	"golang.org/x/net/context"
+)
+
+// Diagnostics holds the information we collect about a service that failed its state conditions
+type Diagnostics struct {
+	Service      string
+	Container    string
+	Status       types.ContainerStatus
+	ExitCode     int
+	OOMKilled    bool
+	RestartCount int
+	State        string
+	Stdout       string
+	Stderr       string
+	FileTails    map[string]string
+}
+
+// Diagnose collects the last lines of output, the container state and the tails of any monitored files for a
+// service that failed.  Anything we are not able to collect is noted in its place rather than treated as an error
+// since we are already reporting a failure.
+func Diagnose(dockerClient client.APIClient, service string, container_name string, conditions types.StateConditions, status types.ContainerStatus, lines int) Diagnostics {
+	diagnostics := Diagnostics{
+		Service:   service,
+		Container: container_name,
+		Status:    status,
+		FileTails: make(map[string]string),
+	}
+
+	// grab the state of the container
+	info, err := dockerClient.ContainerInspect(context.Background(), container_name)
+	if err != nil {
+		diagnostics.State = fmt.Sprintf("could not inspect container: %v", err)
+	} else {
+		diagnostics.State = info.ContainerJSONBase.State.Status
+		diagnostics.ExitCode = info.ContainerJSONBase.State.ExitCode
+		diagnostics.OOMKilled = info.ContainerJSONBase.State.OOMKilled
+		diagnostics.RestartCount = info.ContainerJSONBase.RestartCount
+	}
+
+	// then the output
+	logReadCloser, err := dockerClient.ContainerLogs(context.Background(), container_name, dockerTypes.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       strconv.Itoa(lines),
+	})
+	if err != nil {
+		diagnostics.Stdout = fmt.Sprintf("could not read logs: %v", err)
+	} else {
+		var stdout, stderr bytes.Buffer
+		// the log stream has stdout and stderr multiplexed together
+		_, err := stdcopy.StdCopy(&stdout, &stderr, logReadCloser)
+		logReadCloser.Close()
+		if err != nil {
+			diagnostics.Stdout = fmt.Sprintf("could not read logs: %v", err)
+		} else {
+			diagnostics.Stdout = stdout.String()
+			diagnostics.Stderr = stderr.String()
+		}
+	}
+
+	// and finally any files we were monitoring
+	for _, filename := range monitoredFiles(conditions) {
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			diagnostics.FileTails[filename] = fmt.Sprintf("could not read file: %v", err)
+			continue
+		}
+		diagnostics.FileTails[filename] = tailLines(string(content), lines)
+	}
+
+	return diagnostics
+}
+
+// String formats our diagnostics as a report
+func (d Diagnostics) String() string {
+	var report bytes.Buffer
+	fmt.Fprintf(&report, "==== Failure report for service %v (container %v) ====\n", d.Service, d.Container)
+	fmt.Fprintf(&report, "Condition:     %v\n", d.Status.Condition)
+	fmt.Fprintf(&report, "Message:       %v\n", strings.TrimSpace(d.Status.Message))
+	fmt.Fprintf(&report, "State:         %v\n", d.State)
+	fmt.Fprintf(&report, "Exit code:     %v\n", d.ExitCode)
+	fmt.Fprintf(&report, "OOM killed:    %v\n", d.OOMKilled)
+	fmt.Fprintf(&report, "Restart count: %v\n", d.RestartCount)
+	fmt.Fprintf(&report, "---- STDOUT ----\n%v\n", strings.TrimRight(d.Stdout, "\n"))
+	fmt.Fprintf(&report, "---- STDERR ----\n%v\n", strings.TrimRight(d.Stderr, "\n"))
+	for filename, tail := range d.FileTails {
+		fmt.Fprintf(&report, "---- %v ----\n%v\n", filename, strings.TrimRight(tail, "\n"))
+	}
+	return report.String()
+}
+
+// Write saves our diagnostics to <dir>/<service>/ so they can be collected as build artifacts
+func (d Diagnostics) Write(dir string) error {
+	serviceDir := filepath.Join(dir, d.Service)
+	err := os.MkdirAll(serviceDir, 0755)
+	if err != nil {
+		return err
+	}
+	files := map[string]string{
+		"report.txt": d.String(),
+		"stdout.log": d.Stdout,
+		"stderr.log": d.Stderr,
+	}
+	for filename, tail := range d.FileTails {
+		files[fmt.Sprintf("file_%v", strings.Replace(strings.Trim(filename, "/"), "/", "_", -1))] = tail
+	}
+	for filename, content := range files {
+		err := ioutil.WriteFile(filepath.Join(serviceDir, filename), []byte(content), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// monitoredFiles returns the files (other than STDOUT and STDERR) monitored by conditions and any of its groups
+func monitoredFiles(conditions types.StateConditions) []string {
+	files := make([]string, 0)
+	for _, condition := range conditions.Conditions {
+		if watcher, ok := condition.(handler.FileWatcher); ok {
+			for _, filename := range watcher.MonitoredFiles() {
+				if GetIndex(files, filename) == -1 {
+					files = append(files, filename)
+				}
+			}
+		}
+	}
+	for _, group := range [][]types.StateConditions{conditions.AllOf, conditions.AnyOf, conditions.Sequence} {
+		for _, member := range group {
+			for _, filename := range monitoredFiles(member) {
+				if GetIndex(files, filename) == -1 {
+					files = append(files, filename)
+				}
+			}
+		}
+	}
+	return files
+}
+
+// tailLines returns the last count lines of content
+func tailLines(content string, count int) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if len(lines) > count {
+		lines = lines[len(lines)-count:]
+	}
+	return strings.Join(lines, "\n")
+}
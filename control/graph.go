@@ -0,0 +1,122 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GraphNode holds a service in our dependency graph along with where it came from and what gates it
+type GraphNode struct {
+	Service    string   `json:"service"`
+	Files      []string `json:"files"`
+	DependsOn  []string `json:"depends_on"`
+	Conditions []string `json:"conditions"`
+}
+
+// Graph returns the nodes of our dependency graph in the order the services are started in
+func (p *Project) Graph() ([]GraphNode, error) {
+	ordered, err := p.SortedServices()
+	if err != nil {
+		return nil, err
+	}
+	dependencies := p.Dependencies()
+	nodes := make([]GraphNode, 0)
+	for _, name := range ordered {
+		node := GraphNode{
+			Service:    name,
+			Files:      p.ServiceFiles[name],
+			DependsOn:  dependencies[name],
+			Conditions: make([]string, 0),
+		}
+		if node.Files == nil {
+			node.Files = make([]string, 0)
+		}
+		if conditions, found := p.StateConditions[name]; found {
+			description := strings.TrimRight(DescribeConditions(conditions, ""), "\n")
+			if description != "" {
+				node.Conditions = strings.Split(description, "\n")
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// WriteGraph writes nodes to out in the format provided (dot, mermaid or json)
+func WriteGraph(out io.Writer, nodes []GraphNode, format string) error {
+	switch format {
+	case "dot":
+		writeDOT(out, nodes)
+	case "mermaid":
+		writeMermaid(out, nodes)
+	case "json":
+		content, err := json.MarshalIndent(nodes, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s\n", content)
+	default:
+		return fmt.Errorf("Unknown graph format %v.  Valid formats are dot, mermaid and json", format)
+	}
+	return nil
+}
+
+// writeDOT writes our graph in the graphviz dot format.  Edges point from a dependency to the services that depend on it
+func writeDOT(out io.Writer, nodes []GraphNode) {
+	fmt.Fprintln(out, "digraph services {")
+	fmt.Fprintln(out, "  rankdir=LR;")
+	fmt.Fprintln(out, "  node [shape=box];")
+	for _, node := range nodes {
+		fmt.Fprintf(out, "  %v [label=%v];\n", quoteDOT(node.Service), quoteDOT(strings.Join(label(node), "\n")))
+	}
+	for _, node := range nodes {
+		for _, dep := range node.DependsOn {
+			fmt.Fprintf(out, "  %v -> %v;\n", quoteDOT(dep), quoteDOT(node.Service))
+		}
+	}
+	fmt.Fprintln(out, "}")
+}
+
+// writeMermaid writes our graph as a mermaid flowchart.  Edges point from a dependency to the services that depend on it
+func writeMermaid(out io.Writer, nodes []GraphNode) {
+	// service names can contain characters mermaid does not allow in ids, so we number them instead
+	ids := make(map[string]string)
+	for index, node := range nodes {
+		ids[node.Service] = fmt.Sprintf("s%v", index)
+	}
+	fmt.Fprintln(out, "graph LR")
+	for _, node := range nodes {
+		lines := label(node)
+		for index, line := range lines {
+			lines[index] = strings.Replace(line, "\"", "#quot;", -1)
+		}
+		fmt.Fprintf(out, "  %v[\"%v\"]\n", ids[node.Service], strings.Join(lines, "<br/>"))
+	}
+	for _, node := range nodes {
+		for _, dep := range node.DependsOn {
+			fmt.Fprintf(out, "  %v --> %v\n", ids[dep], ids[node.Service])
+		}
+	}
+}
+
+// label builds the lines used to describe a node
+func label(node GraphNode) []string {
+	lines := []string{node.Service}
+	for _, file := range node.Files {
+		lines = append(lines, fmt.Sprintf("file: %v", file))
+	}
+	for _, condition := range node.Conditions {
+		lines = append(lines, strings.TrimSpace(condition))
+	}
+	return lines
+}
+
+// quoteDOT quotes a string for use as a dot id
+func quoteDOT(value string) string {
+	value = strings.Replace(value, "\\", "\\\\", -1)
+	value = strings.Replace(value, "\"", "\\\"", -1)
+	value = strings.Replace(value, "\n", "\\n", -1)
+	return fmt.Sprintf("\"%v\"", value)
+}
@@ -0,0 +1,42 @@
+package control
+
+import (
+	"fmt"
+)
+
+// Waves groups our services by how deep they are in the dependency graph.  Every service in a wave only depends on
+// services in earlier waves, so each wave can be started in parallel once the waves before it have succeeded.
+func (p *Project) Waves() ([][]string, error) {
+	ordered, err := p.SortedServices()
+	if err != nil {
+		return nil, err
+	}
+	dependencies := p.Dependencies()
+
+	// since we go through our services in order, the depth of each dependency is known before we need it
+	depth := make(map[string]int)
+	waves := make([][]string, 0)
+	for _, name := range ordered {
+		for _, dep := range dependencies[name] {
+			if depth[dep]+1 > depth[name] {
+				depth[name] = depth[dep] + 1
+			}
+		}
+		for len(waves) <= depth[name] {
+			waves = append(waves, make([]string, 0))
+		}
+		waves[depth[name]] = append(waves[depth[name]], name)
+	}
+	return waves, nil
+}
+
+// Image returns the image a service will run after any app versions have been applied, or an empty string if the
+// service is built rather than pulled
+func (p *Project) Image(name string) string {
+	if service, found := p.resolvedServices[name]; found {
+		if image, found := service["image"]; found {
+			return fmt.Sprintf("%v", image)
+		}
+	}
+	return ""
+}
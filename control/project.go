@@ -3,9 +3,11 @@ package control
 import (
 	"fmt"
 	"github.com/twmb/algoimpl/go/graph"
-	"os"
+	"sort"
+	"strings"
 
 	"github.com/dansteen/controlled-compose/types"
+	"github.com/docker/engine-api/client"
 	"github.com/docker/libcompose/cli/logger"
 	config "github.com/docker/libcompose/config"
 	"github.com/docker/libcompose/docker"
@@ -14,22 +16,31 @@ import (
 )
 
 type Project struct {
-	StateConditions map[string]types.StateConditions
-	ComposeProject  project.APIProject
-	Services        map[string]project.Service
-	appVersions     []string
+	StateConditions  map[string]types.StateConditions
+	ComposeProject   project.APIProject
+	Services         map[string]project.Service
+	Files            []string
+	ServiceFiles     map[string][]string
+	ExportedVolumes  map[string][]string
+	appVersions      []string
+	name             string
+	mergedConfig     []byte
+	resolvedServices config.RawServiceMap
 }
 
-// GenProject will generate a Project object using the config files passed in
-func GenProject(name string, files []string, appVersions []string) (Project, error) {
+// GenProject will generate a Project object using the config files passed in.  libcompose talks to docker through
+// dockerClient, or connects with the default options if it is nil.
+func GenProject(name string, files []string, appVersions []string, dockerClient client.APIClient) (Project, error) {
 
 	// create our project object
 	p := Project{
 		StateConditions: make(map[string]types.StateConditions),
+		ExportedVolumes: make(map[string][]string),
 	}
 
 	// set our app verions for consumption by processConfig
 	p.appVersions = appVersions
+	p.name = name
 
 	// process the compose files provided on the command line for additional requirements
 	var composeFiles []string
@@ -42,11 +53,15 @@ func GenProject(name string, files []string, appVersions []string) (Project, err
 		}
 	}
 	// we slurp our configs manually to bypass odd docker working directory behavior
-	configBytes, err := consumeConfigs(composeFiles)
+	configBytes, serviceFiles, err := consumeConfigs(composeFiles)
 	if err != nil {
 		return p, err
 	}
 	composeBytes = append(composeBytes, configBytes)
+	// save these off so we can show people what we resolved
+	p.Files = composeFiles
+	p.ServiceFiles = serviceFiles
+	p.mergedConfig = configBytes
 
 	// create a context for our project
 	p_context := docker.Context{
@@ -59,6 +74,9 @@ func GenProject(name string, files []string, appVersions []string) (Project, err
 			IgnoreMissingConfig: false,
 		},
 	}
+	if dockerClient != nil {
+		p_context.ClientFactory = clientFactory{client: dockerClient}
+	}
 
 	// set up some parse options
 	parse_options := config.ParseOptions{
@@ -81,31 +99,82 @@ func GenProject(name string, files []string, appVersions []string) (Project, err
 
 }
 
+// clientFactory hands libcompose the docker client we were given rather than letting it connect on its own
+type clientFactory struct {
+	client client.APIClient
+}
+
+// Create implements the libcompose client.Factory interface
+func (f clientFactory) Create(service project.Service) client.APIClient {
+	return f.client
+}
+
+// DependencyError describes problems with the depends_on stanzas of our services
+type DependencyError struct {
+	// Missing holds a message for each dependency on a service that is not included in the config
+	Missing []string
+	// Cycle holds the services that depend on each other in a loop, with the first service repeated at the end
+	Cycle []string
+	// Waiting holds the services that were never started because the services they depend on never finished
+	Waiting []string
+}
+
+// Error implements the error interface
+func (e *DependencyError) Error() string {
+	problems := make([]string, 0)
+	for _, missing := range e.Missing {
+		problems = append(problems, fmt.Sprintf("Error: %v", missing))
+	}
+	if len(e.Cycle) > 0 {
+		problems = append(problems, fmt.Sprintf("Error: Services depend on each other in a cycle: %v", strings.Join(e.Cycle, " -> ")))
+	}
+	if len(e.Waiting) > 0 {
+		problems = append(problems, fmt.Sprintf("Error: Could not start all services.  %v were still waiting on their dependencies", strings.Join(e.Waiting, ", ")))
+	}
+	return strings.Join(problems, "\n")
+}
+
 // SortedServices build a sorted list of services based on each services dependencies.
-// We use a topological sort for this
-func (p *Project) SortedServices() []string {
+// We use a topological sort for this.  An error is returned if any dependencies are missing or form a cycle.
+func (p *Project) SortedServices() ([]string, error) {
+	dependencies := p.Dependencies()
+
+	// make sure all of our dependencies exist.  we check all of them so we can report every problem at once
+	dependencyErr := &DependencyError{}
+	for _, name := range sortedNames(dependencies) {
+		for _, dep := range dependencies[name] {
+			if _, found := dependencies[dep]; !found {
+				dependencyErr.Missing = append(dependencyErr.Missing, fmt.Sprintf("Service %v depends on service %v which is not included in the config", name, dep))
+			}
+		}
+	}
+	if len(dependencyErr.Missing) > 0 {
+		return nil, dependencyErr
+	}
+
+	// the topological sort does not tell us if it was unable to order everything, so we look for cycles first
+	if cycle := findCycle(dependencies); cycle != nil {
+		dependencyErr.Cycle = cycle
+		return nil, dependencyErr
+	}
+
 	// create a new graph
 	ourGraph := graph.New(graph.Directed)
 	// a place to store our nodes
 	nodes := make(map[string]graph.Node)
 
 	// add in nodes for each of our services
-	for name, _ := range p.Services {
+	for _, name := range sortedNames(dependencies) {
 		nodes[name] = ourGraph.MakeNode()
 		// hook the data back into the graph (not strictly required)
 		*nodes[name].Value = name
 	}
 
 	// add in our edges
-	for name, service := range p.Services {
-		for _, dep := range service.DependentServices() {
-			// make sure the dependency exists
-			if _, found := nodes[dep.Target]; !found {
-				fmt.Printf("Error: Service %v depends on service %v which is not included in the config\n", name, dep.Target)
-				os.Exit(1)
-			}
+	for _, name := range sortedNames(dependencies) {
+		for _, dep := range dependencies[name] {
 			// add in an edge for this dependency
-			ourGraph.MakeEdge(nodes[dep.Target], nodes[name])
+			ourGraph.MakeEdge(nodes[dep], nodes[name])
 		}
 	}
 
@@ -118,12 +187,102 @@ func (p *Project) SortedServices() []string {
 		orderedServiceNames = append(orderedServiceNames, (*node.Value).(string))
 	}
 
-	return orderedServiceNames
+	return orderedServiceNames, nil
+}
+
+// findCycle looks for services that depend on each other in a loop, and returns the first one it finds as a path
+// that starts and ends with the same service.  It returns nil if there are no cycles.
+func findCycle(dependencies map[string][]string) []string {
+	// services we have finished checking, and the path of services we are currently checking
+	checked := make(map[string]bool)
+	path := make([]string, 0)
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		// if we are already on our path we have come back around to ourselves
+		if index := GetIndex(path, name); index != -1 {
+			return append(append([]string{}, path[index:]...), name)
+		}
+		if checked[name] {
+			return nil
+		}
+		path = append(path, name)
+		for _, dep := range dependencies[name] {
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		checked[name] = true
+		return nil
+	}
+
+	for _, name := range sortedNames(dependencies) {
+		if cycle := visit(name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// sortedNames returns the keys of dependencies in a stable order
+func sortedNames(dependencies map[string][]string) []string {
+	names := make([]string, 0, len(dependencies))
+	for name := range dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Select returns a copy of our project limited to the target services and everything they depend on, directly or
+// through other services.  Services outside of that set are left out of the copy, so anything that works through its
+// services (such as SortedServices and Schedule) will only see the selected ones.  Our own project is left as it is.
+func (p *Project) Select(targets []string) (*Project, error) {
+	// make sure our dependencies are sane before we walk them
+	if _, err := p.SortedServices(); err != nil {
+		return nil, err
+	}
+	dependencies := p.Dependencies()
+
+	selected := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if selected[name] {
+			return
+		}
+		selected[name] = true
+		for _, dep := range dependencies[name] {
+			visit(dep)
+		}
+	}
+	for _, target := range targets {
+		if _, found := p.Services[target]; !found {
+			return nil, fmt.Errorf("Service %v is not included in the config", target)
+		}
+		visit(target)
+	}
+
+	// the copy shares everything else with us, so only the maps we filter are new
+	selection := *p
+	selection.Services = make(map[string]project.Service)
+	selection.StateConditions = make(map[string]types.StateConditions)
+	for name, service := range p.Services {
+		if selected[name] {
+			selection.Services[name] = service
+		}
+	}
+	for name, conditions := range p.StateConditions {
+		if selected[name] {
+			selection.StateConditions[name] = conditions
+		}
+	}
+	return &selection, nil
 }
 
 // Container will return the containers associated with a particular service
-func (p *Project) Containers(name string) ([]project.Container, error) {
-	containers, err := p.Services[name].Containers(context.Background())
+func (p *Project) Containers(ctx context.Context, name string) ([]project.Container, error) {
+	containers, err := p.Services[name].Containers(ctx)
 	return containers, err
 }
 
@@ -0,0 +1,146 @@
+package control_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/dansteen/controlled-compose/control"
+)
+
+var sortedServicesTests = []struct {
+	name         string
+	dependencies map[string][]string
+	// the order we expect, or the problems we expect to be reported instead
+	order   []string
+	missing []string
+	cycle   []string
+}{
+	{
+		name:         "dependencies come before the services that depend on them",
+		dependencies: map[string][]string{"web": {"db", "cache"}, "db": {}, "cache": {"db"}},
+		order:        []string{"db", "cache", "web"},
+	},
+	{
+		name:         "a service that depends on itself",
+		dependencies: map[string][]string{"db": {"db"}, "web": {"db"}},
+		cycle:        []string{"db", "db"},
+	},
+	{
+		name:         "a cycle through several services is reported as the path around it",
+		dependencies: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}, "web": {"a"}},
+		cycle:        []string{"a", "b", "c", "a"},
+	},
+	{
+		name:         "a cycle is found from wherever it is entered",
+		dependencies: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"b"}},
+		cycle:        []string{"b", "c", "b"},
+	},
+	{
+		name:         "every missing dependency is reported at once",
+		dependencies: map[string][]string{"web": {"db", "cache"}, "worker": {"queue"}},
+		missing: []string{
+			"Service web depends on service db which is not included in the config",
+			"Service web depends on service cache which is not included in the config",
+			"Service worker depends on service queue which is not included in the config",
+		},
+	},
+	{
+		name:         "missing dependencies are reported before cycles",
+		dependencies: map[string][]string{"a": {"b"}, "b": {"a", "c"}},
+		missing:      []string{"Service b depends on service c which is not included in the config"},
+	},
+}
+
+func TestSortedServices(t *testing.T) {
+	for _, test := range sortedServicesTests {
+		t.Run(test.name, func(t *testing.T) {
+			project := genProject(t, services(test.dependencies))
+			order, err := project.SortedServices()
+			if test.order != nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(order, test.order) {
+					t.Errorf("expected %v but got %v", test.order, order)
+				}
+				return
+			}
+
+			dependencyErr, ok := err.(*control.DependencyError)
+			if !ok {
+				t.Fatalf("expected a *control.DependencyError but got %T: %v", err, err)
+			}
+			if !reflect.DeepEqual(dependencyErr.Missing, test.missing) {
+				t.Errorf("expected %v to be missing but got %v", test.missing, dependencyErr.Missing)
+			}
+			if !reflect.DeepEqual(dependencyErr.Cycle, test.cycle) {
+				t.Errorf("expected the cycle %v but got %v", test.cycle, dependencyErr.Cycle)
+			}
+		})
+	}
+}
+
+var selectTests = []struct {
+	name    string
+	targets []string
+	// the services we expect to be selected, or whether we expect an error
+	selected []string
+	err      bool
+}{
+	{
+		name:     "a service without dependencies is selected on its own",
+		targets:  []string{"cache"},
+		selected: []string{"cache"},
+	},
+	{
+		name:     "a service keeps its direct and indirect dependencies",
+		targets:  []string{"web"},
+		selected: []string{"api", "cache", "db", "web"},
+	},
+	{
+		name:     "several targets share their dependencies",
+		targets:  []string{"api", "worker"},
+		selected: []string{"api", "cache", "db", "queue", "worker"},
+	},
+	{
+		name:    "a service that is not in the config",
+		targets: []string{"search"},
+		err:     true,
+	},
+}
+
+func TestSelect(t *testing.T) {
+	dependencies := map[string][]string{
+		"db":     {},
+		"cache":  {},
+		"queue":  {},
+		"api":    {"db", "cache"},
+		"web":    {"api"},
+		"worker": {"queue", "db"},
+	}
+	for _, test := range selectTests {
+		t.Run(test.name, func(t *testing.T) {
+			project := genProject(t, services(dependencies))
+			selection, err := project.Select(test.targets)
+			if (err != nil) != test.err {
+				t.Fatalf("expected an error to be %v but got %v", test.err, err)
+			}
+			if err != nil {
+				return
+			}
+			order, err := selection.SortedServices()
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(order)
+			if !reflect.DeepEqual(order, test.selected) {
+				t.Errorf("expected %v to be selected but got %v", test.selected, order)
+			}
+			// the project we selected from is left as it was
+			if len(project.Services) != len(dependencies) {
+				t.Errorf("expected the project to still have %v services but it has %v", len(dependencies), len(project.Services))
+			}
+		})
+	}
+}
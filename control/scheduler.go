@@ -0,0 +1,119 @@
+package control
+
+import (
+	"sort"
+
+	"golang.org/x/net/context"
+)
+
+// scheduleResult holds the outcome of starting a single service
+type scheduleResult struct {
+	name string
+	err  error
+}
+
+// Dependencies returns a map of each service to the names of the services it depends on
+func (p *Project) Dependencies() map[string][]string {
+	dependencies := make(map[string][]string)
+	for name, service := range p.Services {
+		dependencies[name] = make([]string, 0)
+		for _, dep := range service.DependentServices() {
+			dependencies[name] = append(dependencies[name], dep.Target)
+		}
+	}
+	return dependencies
+}
+
+// Schedule walks our dependency graph and calls start for every service once all of the services it depends on
+// have been started successfully.  Services that do not depend on each other are started concurrently, with at most
+// parallelism running at the same time (0 means no limit).  Once start returns an error no new services are started,
+// the context passed to the services that are already starting is cancelled, and the first error is returned once
+// they have finished.
+func (p *Project) Schedule(ctx context.Context, parallelism int, start func(ctx context.Context, name string) error) error {
+	// we use the topological order to break ties so that a parallelism of 1 starts services in the same order as before
+	order, err := p.SortedServices()
+	if err != nil {
+		return err
+	}
+	position := make(map[string]int)
+	for index, name := range order {
+		position[name] = index
+	}
+
+	// work out how many dependencies each service is waiting on, and who is waiting on each service
+	remaining := make(map[string]int)
+	dependents := make(map[string][]string)
+	for name, deps := range p.Dependencies() {
+		remaining[name] = len(deps)
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	// collect the services that are ready to start right away
+	ready := make([]string, 0)
+	for _, name := range order {
+		if remaining[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	// once one service has failed there is no point in waiting on the others, some of which may never finish on
+	// their own
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan scheduleResult)
+	running := 0
+	started := 0
+	var firstErr error
+	for {
+		// start as many services as we are allowed to
+		for firstErr == nil && len(ready) > 0 && (parallelism <= 0 || running < parallelism) {
+			name := ready[0]
+			ready = ready[1:]
+			running++
+			started++
+			go func(name string) {
+				results <- scheduleResult{name: name, err: start(ctx, name)}
+			}(name)
+		}
+		if running == 0 {
+			break
+		}
+
+		// then wait for one of them to finish
+		result := <-results
+		running--
+		if result.err != nil {
+			if firstErr == nil {
+				firstErr = result.err
+				cancel()
+			}
+			continue
+		}
+		// anything that was only waiting on this service can now be started
+		for _, dependent := range dependents[result.name] {
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+		sort.Slice(ready, func(i, j int) bool { return position[ready[i]] < position[ready[j]] })
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	// if we were not able to start everything, some services are waiting on each other
+	if started != len(order) {
+		dependencyErr := &DependencyError{}
+		for _, name := range order {
+			if remaining[name] > 0 {
+				dependencyErr.Waiting = append(dependencyErr.Waiting, name)
+			}
+		}
+		return dependencyErr
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package control_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dansteen/controlled-compose/control"
+	"github.com/dansteen/controlled-compose/fakedocker"
+	"golang.org/x/net/context"
+)
+
+// genProject reads config in as a compose file.  Nothing is sent to docker, but we give it a fake one anyway so we
+// never connect to a real one by accident.
+func genProject(t *testing.T, config string) control.Project {
+	file := filepath.Join(t.TempDir(), "docker-compose.yml")
+	if err := ioutil.WriteFile(file, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	project, err := control.GenProject("controltest", []string{file}, nil, fakedocker.New())
+	if err != nil {
+		t.Fatalf("could not read the project: %v", err)
+	}
+	return project
+}
+
+// services builds a compose file with a service for each key of dependencies, which depends on the services listed
+// for it
+func services(dependencies map[string][]string) string {
+	names := make([]string, 0, len(dependencies))
+	for name := range dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	config := "version: '2'\nservices:\n"
+	for _, name := range names {
+		config += fmt.Sprintf("  %v:\n    image: busybox\n", name)
+		if len(dependencies[name]) > 0 {
+			config += "    depends_on:\n"
+			for _, dep := range dependencies[name] {
+				config += fmt.Sprintf("      - %v\n", dep)
+			}
+		}
+	}
+	return config
+}
+
+var scheduleTests = []struct {
+	name         string
+	dependencies map[string][]string
+	parallelism  int
+	// together are services that have to be starting at the same time.  Each of them waits for the rest before it
+	// finishes, so the test hangs (and times out) if the scheduler does not start them together
+	together []string
+	// fail returns an error as soon as it is started, and block waits until it is cancelled
+	fail  string
+	block string
+	// the services we expect to be started, how many we expect to be starting at once at most, and whether we expect
+	// an error
+	started    []string
+	concurrent int
+	err        bool
+}{
+	{
+		name:         "a chain starts one at a time",
+		dependencies: map[string][]string{"a": {}, "b": {"a"}, "c": {"b"}},
+		started:      []string{"a", "b", "c"},
+		concurrent:   1,
+	},
+	{
+		name:         "services that do not depend on each other start together",
+		dependencies: map[string][]string{"db": {}, "cache": {}, "web": {"db", "cache"}},
+		together:     []string{"db", "cache"},
+		started:      []string{"cache", "db", "web"},
+		concurrent:   2,
+	},
+	{
+		name:         "parallelism limits how many start at once",
+		dependencies: map[string][]string{"a": {}, "b": {}, "c": {}},
+		parallelism:  2,
+		together:     []string{"a", "b"},
+		started:      []string{"a", "b", "c"},
+		concurrent:   2,
+	},
+	{
+		name:         "a parallelism of 1 starts one at a time",
+		dependencies: map[string][]string{"a": {}, "b": {}, "c": {}},
+		parallelism:  1,
+		started:      []string{"a", "b", "c"},
+		concurrent:   1,
+	},
+	{
+		name:         "a failure cancels the services that are starting and starts nothing new",
+		dependencies: map[string][]string{"db": {}, "cache": {}, "web": {"db", "cache"}},
+		together:     []string{"db", "cache"},
+		fail:         "db",
+		block:        "cache",
+		started:      []string{"cache", "db"},
+		concurrent:   2,
+		err:          true,
+	},
+	{
+		name:         "a cycle is reported before anything is started",
+		dependencies: map[string][]string{"a": {"b"}, "b": {"a"}},
+		started:      []string{},
+		err:          true,
+	},
+}
+
+func TestSchedule(t *testing.T) {
+	for _, test := range scheduleTests {
+		t.Run(test.name, func(t *testing.T) {
+			project := genProject(t, services(test.dependencies))
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var lock sync.Mutex
+			changed := sync.NewCond(&lock)
+			started := make([]string, 0)
+			finished := make(map[string]bool)
+			running := 0
+			concurrent := 0
+			err := project.Schedule(ctx, test.parallelism, func(serviceCtx context.Context, name string) error {
+				lock.Lock()
+				defer lock.Unlock()
+				for _, dep := range test.dependencies[name] {
+					if !finished[dep] {
+						t.Errorf("%v was started before %v, which it depends on, had finished", name, dep)
+					}
+				}
+				started = append(started, name)
+				running++
+				if running > concurrent {
+					concurrent = running
+				}
+				changed.Broadcast()
+				defer func() {
+					running--
+					finished[name] = true
+				}()
+
+				// wait for the rest of our group to start
+				if control.GetIndex(test.together, name) != -1 {
+					for ctx.Err() == nil && !startedAll(started, test.together) {
+						wait(ctx, changed)
+					}
+				}
+				switch name {
+				case test.fail:
+					return fmt.Errorf("%v failed", name)
+				case test.block:
+					for serviceCtx.Err() == nil && ctx.Err() == nil {
+						wait(serviceCtx, changed)
+					}
+					return serviceCtx.Err()
+				}
+				return nil
+			})
+			if ctx.Err() != nil {
+				t.Fatalf("the scheduler did not finish: %v", ctx.Err())
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			if (err != nil) != test.err {
+				t.Errorf("expected an error to be %v but got %v", test.err, err)
+			}
+			sort.Strings(started)
+			if !reflect.DeepEqual(started, test.started) {
+				t.Errorf("expected %v to be started but %v were", test.started, started)
+			}
+			if concurrent != test.concurrent {
+				t.Errorf("expected at most %v services to be starting at once but there were %v", test.concurrent, concurrent)
+			}
+		})
+	}
+}
+
+// startedAll returns true if every one of names has been started
+func startedAll(started []string, names []string) bool {
+	for _, name := range names {
+		if control.GetIndex(started, name) == -1 {
+			return false
+		}
+	}
+	return true
+}
+
+// wait waits on changed until it is signalled or ctx is cancelled.  The lock of changed must be held.
+func wait(ctx context.Context, changed *sync.Cond) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			changed.L.Lock()
+			changed.Broadcast()
+			changed.L.Unlock()
+		case <-done:
+		}
+	}()
+	changed.Wait()
+}
+
+var waveTests = []struct {
+	name         string
+	dependencies map[string][]string
+	waves        [][]string
+}{
+	{
+		name:         "a chain has a wave for each service",
+		dependencies: map[string][]string{"a": {}, "b": {"a"}, "c": {"b"}},
+		waves:        [][]string{{"a"}, {"b"}, {"c"}},
+	},
+	{
+		name:         "independent services share a wave",
+		dependencies: map[string][]string{"db": {}, "cache": {}, "web": {"db", "cache"}},
+		waves:        [][]string{{"cache", "db"}, {"web"}},
+	},
+	{
+		name:         "a service goes in the wave after its deepest dependency",
+		dependencies: map[string][]string{"a": {}, "b": {"a"}, "c": {"a", "b"}, "d": {}},
+		waves:        [][]string{{"a", "d"}, {"b"}, {"c"}},
+	},
+}
+
+func TestWaves(t *testing.T) {
+	for _, test := range waveTests {
+		t.Run(test.name, func(t *testing.T) {
+			project := genProject(t, services(test.dependencies))
+			waves, err := project.Waves()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// services in the same wave are in no particular order
+			for _, wave := range waves {
+				sort.Strings(wave)
+			}
+			if !reflect.DeepEqual(waves, test.waves) {
+				t.Errorf("expected %v but got %v", test.waves, waves)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package control
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/engine-api/client"
+	dockerTypes "github.com/docker/engine-api/types"
+	"github.com/docker/engine-api/types/filters"
+	"github.com/docker/libcompose/project"
+	"github.com/docker/libcompose/project/options"
+	"golang.org/x/net/context"
+)
+
+// ReverseServices returns the services in the reverse of the order they are started in, which is the order
+// they should be stopped in
+func (p *Project) ReverseServices() ([]string, error) {
+	ordered, err := p.SortedServices()
+	if err != nil {
+		return nil, err
+	}
+	reversed := make([]string, 0, len(ordered))
+	for index := len(ordered) - 1; index >= 0; index-- {
+		reversed = append(reversed, ordered[index])
+	}
+	return reversed, nil
+}
+
+// StopServices stops and removes the containers for each of the named services in the order provided.  Services
+// are given timeout seconds to stop before they are killed, and our progress is written to out.  A service that can
+// not be stopped does not stop us from stopping the rest, and an error listing every service that failed is returned
+// once we are done.
+func (p *Project) StopServices(ctx context.Context, out io.Writer, names []string, timeout int, removeVolumes bool) error {
+	failures := make([]string, 0)
+	for _, name := range names {
+		fmt.Fprintf(out, "Stopping %v:  ", name)
+		err := p.Services[name].Stop(ctx, timeout)
+		if err == nil {
+			err = p.Services[name].Delete(ctx, options.Delete{RemoveVolume: removeVolumes})
+		}
+		if err != nil {
+			fmt.Fprintf(out, "failed: %v\n", err)
+			failures = append(failures, fmt.Sprintf("%v: %v", name, err))
+			continue
+		}
+		fmt.Fprintf(out, "%v\n", "done")
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%v of %v services could not be stopped: %v", len(failures), len(names), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// composeName returns our project name the way libcompose uses it to name containers, networks and volumes.  It
+// strips out anything other than lower case letters and numbers, so this may be different from the name we were given.
+func (p *Project) composeName() string {
+	if composeProject, ok := p.ComposeProject.(*project.Project); ok && composeProject.Name != "" {
+		return composeProject.Name
+	}
+	return p.name
+}
+
+// RemoveNetworks removes the networks that were created for the project.  External networks are left alone
+func (p *Project) RemoveNetworks(ctx context.Context, out io.Writer, dockerClient client.APIClient) error {
+	// every project gets a default network in addition to the ones it declares
+	networks := []string{fmt.Sprintf("%v_default", p.composeName())}
+	for name, networkConfig := range p.ComposeProject.(*project.Project).NetworkConfigs {
+		if networkConfig != nil && networkConfig.External.External {
+			continue
+		}
+		networks = append(networks, fmt.Sprintf("%v_%v", p.composeName(), name))
+	}
+
+	existing, err := dockerClient.NetworkList(ctx, dockerTypes.NetworkListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, network := range existing {
+		if GetIndex(networks, network.Name) == -1 {
+			continue
+		}
+		fmt.Fprintf(out, "Removing network %v:  ", network.Name)
+		err := dockerClient.NetworkRemove(ctx, network.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%v\n", "done")
+	}
+	return nil
+}
+
+// RemoveVolumes removes the named volumes that were created for the project.  External volumes are left alone, as
+// are volumes that were never created (such as when up was stopped early)
+func (p *Project) RemoveVolumes(ctx context.Context, out io.Writer, dockerClient client.APIClient) error {
+	volumes := make([]string, 0)
+	for name, volumeConfig := range p.ComposeProject.(*project.Project).VolumeConfigs {
+		if volumeConfig != nil && volumeConfig.External.External {
+			continue
+		}
+		volumes = append(volumes, fmt.Sprintf("%v_%v", p.composeName(), name))
+	}
+
+	existing, err := dockerClient.VolumeList(ctx, filters.NewArgs())
+	if err != nil {
+		return err
+	}
+	for _, volume := range existing.Volumes {
+		if GetIndex(volumes, volume.Name) == -1 {
+			continue
+		}
+		fmt.Fprintf(out, "Removing volume %v:  ", volume.Name)
+		err := dockerClient.VolumeRemove(ctx, volume.Name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%v\n", "done")
+	}
+	return nil
+}
+
+// ExportDirs returns the directories created on the host to export the monitored files of our services.  These are
+// the directories recorded in ExportedVolumes, along with any that the containers of our services have mounted.  The
+// directories are named after the pid of the run that created them, so the ones from an earlier run of up can only be
+// found through its containers.
+func (p *Project) ExportDirs(ctx context.Context, dockerClient client.APIClient) ([]string, error) {
+	currDir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	exportRoot := filepath.Join(currDir, "controlled_compose_")
+	dirs := make([]string, 0)
+	add := func(path string) {
+		if strings.HasPrefix(path, exportRoot) && GetIndex(dirs, path) == -1 {
+			dirs = append(dirs, path)
+		}
+	}
+
+	for _, volumes := range p.ExportedVolumes {
+		for _, volume := range volumes {
+			for _, part := range strings.Split(volume, ":") {
+				add(part)
+			}
+		}
+	}
+	for name := range p.Services {
+		containers, err := p.Containers(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, container := range containers {
+			info, err := dockerClient.ContainerInspect(ctx, container.Name())
+			if err != nil {
+				return nil, err
+			}
+			// our exports are mounted from the host, so they are the source of the mount.  the destination is the
+			// directory being monitored inside the container, which is never one of ours
+			for _, mount := range info.Mounts {
+				add(mount.Source)
+			}
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// RemoveExports removes dirs, as found by ExportDirs.  The controlled_compose_<pid> directory holding them is shared by
+// every service in a run, so it is only removed once it is empty.
+func RemoveExports(out io.Writer, dirs []string) error {
+	for _, dir := range dirs {
+		fmt.Fprintf(out, "Removing %v:  ", dir)
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%v\n", "done")
+		if parent := filepath.Dir(dir); strings.HasPrefix(filepath.Base(parent), "controlled_compose_") {
+			// this fails if other services still have exports in it, which is what we want
+			os.Remove(parent)
+		}
+	}
+	return nil
+}
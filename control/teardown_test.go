@@ -0,0 +1,63 @@
+package control_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dansteen/controlled-compose/control"
+	"github.com/dansteen/controlled-compose/fakedocker"
+	dockerTypes "github.com/docker/engine-api/types"
+	"golang.org/x/net/context"
+)
+
+func TestExportDirs(t *testing.T) {
+	currDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	thisRun := filepath.Join(currDir, fmt.Sprintf("controlled_compose_%v", os.Getpid()), "db")
+	earlierRun := filepath.Join(currDir, "controlled_compose_1", "web")
+
+	file := filepath.Join(t.TempDir(), "docker-compose.yml")
+	config := `version: '2'
+services:
+  db:
+    image: postgres
+    state_conditions: {filemonitor: [{file: /var/log/app/app.log, regex: ready, status: success}]}
+  web:
+    image: nginx
+`
+	if err := ioutil.WriteFile(file, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fake := fakedocker.New()
+	project, err := control.GenProject("controltest", []string{file}, nil, fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// web is left over from an earlier run, so we only know about its export from its mounts.  A directory that is
+	// only the destination of a mount is inside the container, so it is not one of ours even if the name matches
+	labels := func(service string) map[string]string {
+		return map[string]string{"com.docker.compose.project": "controltest", "com.docker.compose.service": service}
+	}
+	fake.Run(fakedocker.Container{Name: "controltest_db_1", Service: "db", Labels: labels("db"), Mounts: []dockerTypes.MountPoint{
+		{Source: thisRun, Destination: "/var/log/app"},
+	}})
+	fake.Run(fakedocker.Container{Name: "controltest_web_1", Service: "web", Labels: labels("web"), Mounts: []dockerTypes.MountPoint{
+		{Source: earlierRun, Destination: "/var/log/nginx"},
+		{Source: "/srv/static", Destination: filepath.Join(currDir, "controlled_compose_2", "web")},
+	}})
+
+	dirs, err := project.ExportDirs(context.Background(), fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{earlierRun, thisRun}; !reflect.DeepEqual(dirs, expected) {
+		t.Errorf("expected %v but got %v", expected, dirs)
+	}
+}
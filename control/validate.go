@@ -0,0 +1,199 @@
+package control
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	yaml "github.com/cloudfoundry-incubator/candiedyaml"
+	"github.com/dansteen/controlled-compose/handler"
+)
+
+// ValidationError describes a single problem found in a compose file
+type ValidationError struct {
+	File    string
+	Service string
+	Path    string
+	Message string
+}
+
+// Error implements the error interface
+func (e ValidationError) Error() string {
+	if e.Service == "" {
+		return fmt.Sprintf("%v: %v: %v", e.File, e.Path, e.Message)
+	}
+	return fmt.Sprintf("%v: service %v: %v: %v", e.File, e.Service, e.Path, e.Message)
+}
+
+// Validate checks the require and state_conditions stanzas of each file, and every file they require.  State
+// conditions are checked with the same parsers that read them in when the project is generated.  Problems with the
+// content of the files are returned as a list of ValidationErrors, while an error is only returned if a file could
+// not be read or parsed at all.
+func Validate(files []string) ([]ValidationError, error) {
+	problems := make([]ValidationError, 0)
+	seen := make([]string, 0)
+	for _, file := range files {
+		var err error
+		problems, seen, err = validateFile(file, problems, seen)
+		if err != nil {
+			return problems, err
+		}
+	}
+	return problems, nil
+}
+
+// validateFile validates a single file and then recurses into the files it requires
+func validateFile(file string, problems []ValidationError, seen []string) ([]ValidationError, []string, error) {
+	seen = append(seen, file)
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return problems, seen, err
+	}
+	var raw map[interface{}]interface{}
+	err = yaml.Unmarshal(content, &raw)
+	if err != nil {
+		return problems, seen, fmt.Errorf("%v: %v", file, err)
+	}
+
+	// check our requires
+	requires := make([]string, 0)
+	if requireRaw, found := raw["require"]; found {
+		switch require := requireRaw.(type) {
+		case string:
+			requires = append(requires, require)
+		case []interface{}:
+			for index, item := range require {
+				if value, ok := item.(string); ok {
+					requires = append(requires, value)
+				} else {
+					problems = append(problems, ValidationError{File: file, Path: fmt.Sprintf("require[%v]", index), Message: fmt.Sprintf("expected a file name but found %v", handler.Describe(item))})
+				}
+			}
+		default:
+			problems = append(problems, ValidationError{File: file, Path: "require", Message: fmt.Sprintf("expected a file name or a list of file names but found %v", handler.Describe(requireRaw))})
+		}
+	}
+	for _, require := range requires {
+		// requires are relative to the file being processed
+		path := filepath.Join(filepath.Dir(file), require)
+		if _, err := os.Stat(path); err != nil {
+			problems = append(problems, ValidationError{File: file, Path: "require", Message: fmt.Sprintf("required file %v does not exist", path)})
+		}
+	}
+
+	// then the state conditions of each service
+	if servicesRaw, found := raw["services"]; found {
+		services, ok := servicesRaw.(map[interface{}]interface{})
+		if ok {
+			for _, name := range sortedKeys(services) {
+				service, ok := services[name].(map[interface{}]interface{})
+				if !ok {
+					continue
+				}
+				if conditions, found := service["state_conditions"]; found {
+					for _, message := range checkStateConditions(conditions, "state_conditions") {
+						problems = append(problems, ValidationError{File: file, Service: name, Path: message.path, Message: message.message})
+					}
+				}
+			}
+		}
+	}
+
+	// and finally the files we require
+	for _, require := range requires {
+		path := filepath.Join(filepath.Dir(file), require)
+		if GetIndex(seen, path) == -1 {
+			if _, err := os.Stat(path); err == nil {
+				problems, seen, err = validateFile(path, problems, seen)
+				if err != nil {
+					return problems, seen, err
+				}
+			}
+		}
+	}
+	return problems, seen, nil
+}
+
+// conditionProblem holds a problem found while checking our state conditions, and where it was found
+type conditionProblem struct {
+	path    string
+	message string
+}
+
+// checkStateConditions validates a state_conditions stanza, or a member of a group inside of one, and returns each
+// problem found.  Every key is handed to the parser that reads it in when the project is generated, so validate can
+// not accept anything that would be rejected later.  path is the key path to value
+func checkStateConditions(value interface{}, path string) []conditionProblem {
+	problems := make([]conditionProblem, 0)
+	valueMap, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return append(problems, conditionProblem{path: path, message: fmt.Sprintf("expected a map but found %v", handler.Describe(value))})
+	}
+	for _, key := range sortedKeys(valueMap) {
+		keyPath := fmt.Sprintf("%v.%v", path, key)
+		if parse, registered := handler.LookupCondition(key); registered {
+			problems = append(problems, checkCondition(parse, valueMap[key], keyPath)...)
+			continue
+		}
+		switch key {
+		case "retry":
+			// we retry by recreating the whole service, so this only makes sense for the service as a whole
+			if path != "state_conditions" {
+				problems = append(problems, conditionProblem{path: keyPath, message: "retry can only be set directly under state_conditions"})
+				continue
+			}
+			retryConfig, err := handler.NewFields("", keyPath, valueMap[key])
+			if err == nil {
+				_, err = parseRetry(retryConfig)
+			}
+			problems = append(problems, parseProblems(err, keyPath)...)
+		case "all_of", "any_of", "sequence":
+			members, ok := valueMap[key].([]interface{})
+			if !ok {
+				problems = append(problems, conditionProblem{path: keyPath, message: fmt.Sprintf("expected a list but found %v", handler.Describe(valueMap[key]))})
+				continue
+			}
+			for index, member := range members {
+				problems = append(problems, checkStateConditions(member, fmt.Sprintf("%v[%v]", keyPath, index))...)
+			}
+		default:
+			problems = append(problems, conditionProblem{path: keyPath, message: "unknown key"})
+		}
+	}
+	return problems
+}
+
+// checkCondition validates the config of a registered condition by parsing it
+func checkCondition(parse handler.ConditionParser, value interface{}, path string) []conditionProblem {
+	_, err := parse(handler.ParseContext{
+		Path: path,
+		// we are only checking the config so there is nothing to export
+		ExportDir: func(dir string) (string, error) { return dir, nil },
+	}, value)
+	return parseProblems(err, path)
+}
+
+// parseProblems turns the error returned by one of our parsers into a problem.  Parsers stop at the first problem
+// they find, so at most one is returned.
+func parseProblems(err error, path string) []conditionProblem {
+	if err == nil {
+		return []conditionProblem{}
+	}
+	if fieldErr, ok := err.(*handler.FieldError); ok {
+		return []conditionProblem{{path: fieldErr.Field, message: fieldErr.Message}}
+	}
+	return []conditionProblem{{path: path, message: err.Error()}}
+}
+
+// sortedKeys returns the keys of a map read in by the yaml parser as sorted strings, so our errors come out in a
+// stable order
+func sortedKeys(values map[interface{}]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, fmt.Sprintf("%v", key))
+	}
+	sort.Strings(keys)
+	return keys
+}
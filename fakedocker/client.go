@@ -0,0 +1,329 @@
+// Fakedocker is an in-memory stand in for the docker server.  It implements the calls our state condition handlers
+// and commands make, so the way services are decided can be exercised without a daemon:
+//
+//	fake := fakedocker.New()
+//	fake.Run(fakedocker.Container{Name: "db", Service: "db.local"})
+//	container := handler.Container{Client: fake, Name: "db", Events: fake.ServiceEvents("db.local"), Out: os.Stdout}
+//	go handler.Evaluate(ctx, container, conditions, container_status)
+//	fake.WaitForListener(ctx, "db")
+//	fake.Stdout("db", "database system is ready to accept connections")
+//	fake.Exit("db", 0)
+//
+// It also implements the calls libcompose makes to create, start and stop the containers of a project, so a whole
+// compose.Composition can be brought up against it with compose.WithDockerClient.  OnStart lets the scenario decide
+// what each container does once it has been started.
+//
+// Any call to the docker api that is not implemented here panics, so it is obvious when a handler starts relying
+// on something new.
+package fakedocker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/docker/engine-api/client"
+	dockerTypes "github.com/docker/engine-api/types"
+	containertypes "github.com/docker/engine-api/types/container"
+	"github.com/docker/engine-api/types/network"
+	"golang.org/x/net/context"
+)
+
+// Container describes a container that the fake docker server knows about
+type Container struct {
+	Name string
+	// ID defaults to a made up id based on Name
+	ID string
+	// Service is the compose service the container belongs to.  Its events are sent to ServiceEvents for the service
+	Service string
+	Labels  map[string]string
+	// IP is the address of the container on its network
+	IP string
+	// Image is the image the container was created from
+	Image string
+	// Mounts are the volumes of the container
+	Mounts []dockerTypes.MountPoint
+}
+
+// logLine is a single line written by a container
+type logLine struct {
+	stderr bool
+	text   string
+	// written is when the container wrote the line
+	written time.Time
+}
+
+// state is everything we track about a container
+type state struct {
+	Container
+	running   bool
+	exitCode  int
+	oomKilled bool
+	health    string
+	lines     []logLine
+	// exec decides the exit code of commands run inside the container
+	exec func(command []string) int
+	// networks are the networks the container has been connected to
+	networks map[string]*network.EndpointSettings
+}
+
+// Client is a fake docker server that can be passed anywhere a client.APIClient is expected
+type Client struct {
+	// everything we do not implement is left to the nil interface, which panics when called
+	client.APIClient
+
+	lock sync.Mutex
+	// changed is signalled whenever a container writes output or stops, so log streams can wake up
+	changed     *sync.Cond
+	containers  map[string]*state
+	order       []string
+	execs       map[string]*execState
+	subscribers map[*subscriber]bool
+	// created counts the containers created through ContainerCreate, so each one gets its own id
+	created int
+	// onStart is called each time a container is started through ContainerStart
+	onStart func(name string)
+	// removed holds the name of each container removed through ContainerRemove, and whether its volumes went with it
+	removed map[string]bool
+}
+
+// New creates a fake docker server with no containers
+func New() *Client {
+	c := &Client{
+		containers:  make(map[string]*state),
+		execs:       make(map[string]*execState),
+		subscribers: make(map[*subscriber]bool),
+		removed:     make(map[string]bool),
+	}
+	c.changed = sync.NewCond(&c.lock)
+	return c
+}
+
+// Run adds a running container to the server, and sends a start event for it
+func (c *Client) Run(container Container) {
+	if container.ID == "" {
+		container.ID = fmt.Sprintf("%x", container.Name)
+	}
+	if container.Labels == nil {
+		container.Labels = make(map[string]string)
+	}
+	if container.Service != "" {
+		container.Labels["com.docker.compose.service"] = container.Service
+	}
+
+	c.lock.Lock()
+	if _, found := c.containers[container.Name]; !found {
+		c.order = append(c.order, container.Name)
+	}
+	c.containers[container.Name] = &state{Container: container, running: true, networks: make(map[string]*network.EndpointSettings)}
+	c.lock.Unlock()
+
+	c.publish(container, "start")
+}
+
+// Stdout has the container write lines to its STDOUT
+func (c *Client) Stdout(name string, lines ...string) {
+	c.write(name, false, lines)
+}
+
+// Stderr has the container write lines to its STDERR
+func (c *Client) Stderr(name string, lines ...string) {
+	c.write(name, true, lines)
+}
+
+// write adds lines to the output of a container and wakes up anybody following it
+func (c *Client) write(name string, stderr bool, lines []string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	state := c.mustFind(name)
+	for _, line := range lines {
+		state.lines = append(state.lines, logLine{stderr: stderr, text: line, written: time.Now()})
+	}
+	c.changed.Broadcast()
+}
+
+// Exit stops the container with code, closes any log streams following it and sends a die event for it
+func (c *Client) Exit(name string, code int) {
+	c.lock.Lock()
+	state := c.mustFind(name)
+	c.stop(state, code)
+	container := state.Container
+	c.lock.Unlock()
+
+	c.publish(container, "die")
+}
+
+// stop marks the container as exited with code and wakes up anybody following its logs.  The lock must be held.
+func (c *Client) stop(state *state, code int) {
+	state.running = false
+	state.exitCode = code
+	c.changed.Broadcast()
+}
+
+// OOMKill stops the container as if the kernel had killed it for running out of memory
+func (c *Client) OOMKill(name string) {
+	c.lock.Lock()
+	c.mustFind(name).oomKilled = true
+	c.lock.Unlock()
+	c.publish(c.container(name), "oom")
+	c.Exit(name, 137)
+}
+
+// SetHealth changes the status of the HEALTHCHECK of the container, and sends a health_status event for it
+func (c *Client) SetHealth(name string, health string) {
+	c.lock.Lock()
+	c.mustFind(name).health = health
+	c.lock.Unlock()
+	c.publish(c.container(name), fmt.Sprintf("health_status: %v", health))
+}
+
+// container returns the description of the container called name
+func (c *Client) container(name string) Container {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.mustFind(name).Container
+}
+
+// mustFind returns the container called name.  The scenario is broken if it does not exist, so we panic.  The lock
+// must be held.
+func (c *Client) mustFind(name string) *state {
+	state, found := c.containers[name]
+	if !found {
+		panic(fmt.Sprintf("fakedocker: there is no container called %v", name))
+	}
+	return state
+}
+
+// find looks a container up by name or id the way docker does.  The lock must be held.
+func (c *Client) find(nameOrID string) (*state, error) {
+	if state, found := c.containers[nameOrID]; found {
+		return state, nil
+	}
+	for _, state := range c.containers {
+		if state.ID == nameOrID || "/"+state.Name == nameOrID {
+			return state, nil
+		}
+	}
+	return nil, fmt.Errorf("Error: No such container: %v", nameOrID)
+}
+
+// ContainerInspect implements client.APIClient.ContainerInspect
+func (c *Client) ContainerInspect(ctx context.Context, nameOrID string) (dockerTypes.ContainerJSON, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	state, err := c.find(nameOrID)
+	if err != nil {
+		return dockerTypes.ContainerJSON{}, err
+	}
+
+	status := "exited"
+	if state.running {
+		status = "running"
+	}
+	info := dockerTypes.ContainerJSON{
+		ContainerJSONBase: &dockerTypes.ContainerJSONBase{
+			ID:   state.ID,
+			Name: "/" + state.Name,
+			State: &dockerTypes.ContainerState{
+				Status:    status,
+				Running:   state.running,
+				ExitCode:  state.exitCode,
+				OOMKilled: state.oomKilled,
+			},
+		},
+		Config: &containertypes.Config{
+			Image:  state.Image,
+			Labels: state.Labels,
+		},
+		Mounts: state.Mounts,
+		NetworkSettings: &dockerTypes.NetworkSettings{
+			Networks: make(map[string]*network.EndpointSettings),
+		},
+	}
+	if state.Image != "" {
+		info.ContainerJSONBase.Image = imageID(state.Image)
+	}
+	for name, endpoint := range state.networks {
+		info.NetworkSettings.Networks[name] = endpoint
+	}
+	if state.health != "" {
+		info.ContainerJSONBase.State.Health = &dockerTypes.Health{Status: state.health}
+	}
+	if state.IP != "" {
+		info.NetworkSettings.Networks["bridge"] = &network.EndpointSettings{IPAddress: state.IP}
+	}
+	return info, nil
+}
+
+// ContainerList implements client.APIClient.ContainerList.  Label filters are applied, as libcompose uses them to find
+// the containers of a service, but any other filters are ignored.
+func (c *Client) ContainerList(ctx context.Context, options dockerTypes.ContainerListOptions) ([]dockerTypes.Container, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	containers := make([]dockerTypes.Container, 0, len(c.order))
+	for _, name := range c.order {
+		state := c.containers[name]
+		if !state.running && !options.All {
+			continue
+		}
+		if !options.Filter.MatchKVList("label", state.Labels) {
+			continue
+		}
+		listed := dockerTypes.Container{
+			ID:     state.ID,
+			Names:  []string{"/" + state.Name},
+			Image:  state.Image,
+			Labels: state.Labels,
+			Mounts: state.Mounts,
+			State:  "exited",
+			Status: fmt.Sprintf("Exited (%v)", state.exitCode),
+		}
+		if state.running {
+			listed.State = "running"
+			listed.Status = "Up"
+		}
+		containers = append(containers, listed)
+	}
+	return containers, nil
+}
+
+// Removed returns the name of every container that has been removed through ContainerRemove, and whether it was
+// asked to remove the volumes of the container as well
+func (c *Client) Removed() map[string]bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	removed := make(map[string]bool)
+	for name, volumes := range c.removed {
+		removed[name] = volumes
+	}
+	return removed
+}
+
+// ContainerRemove implements client.APIClient.ContainerRemove
+func (c *Client) ContainerRemove(ctx context.Context, nameOrID string, options dockerTypes.ContainerRemoveOptions) error {
+	c.lock.Lock()
+	state, err := c.find(nameOrID)
+	if err != nil {
+		c.lock.Unlock()
+		return err
+	}
+	if state.running && !options.Force {
+		c.lock.Unlock()
+		return fmt.Errorf("Error response from daemon: You cannot remove a running container %v. Stop the container before attempting removal or use -f", state.ID)
+	}
+	delete(c.containers, state.Name)
+	for index, name := range c.order {
+		if name == state.Name {
+			c.order = append(c.order[:index], c.order[index+1:]...)
+			break
+		}
+	}
+	c.removed[state.Name] = options.RemoveVolumes
+	// anything still following the logs of the container finishes
+	state.running = false
+	c.changed.Broadcast()
+	c.lock.Unlock()
+
+	c.publish(state.Container, "destroy")
+	return nil
+}
@@ -0,0 +1,191 @@
+package fakedocker
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	dockerTypes "github.com/docker/engine-api/types"
+	eventtypes "github.com/docker/engine-api/types/events"
+	"github.com/docker/libcompose/project/events"
+	"golang.org/x/net/context"
+)
+
+// message is an event along with the container it happened to
+type message struct {
+	container Container
+	event     events.ContainerEvent
+}
+
+// subscriber is somebody listening for the events of the containers that match
+type subscriber struct {
+	ctx   context.Context
+	match func(container Container) bool
+	queue chan message
+}
+
+// publish sends event for container to everybody listening for it.  Nobody reading their events should hold up the
+// rest of the scenario, so events are dropped for subscribers that have fallen too far behind.
+func (c *Client) publish(container Container, event string) {
+	containerEvent := events.ContainerEvent{
+		Service: container.Service,
+		Event:   event,
+		ID:      container.ID,
+		Time:    time.Now(),
+		Type:    "container",
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for sub := range c.subscribers {
+		if !sub.match(container) {
+			continue
+		}
+		select {
+		case sub.queue <- message{container: container, event: containerEvent}:
+		default:
+		}
+	}
+}
+
+// subscribe returns a stream of the events for the containers that match, which ends once ctx is cancelled
+func (c *Client) subscribe(ctx context.Context, match func(container Container) bool) <-chan message {
+	sub := &subscriber{ctx: ctx, match: match, queue: make(chan message, 100)}
+	c.lock.Lock()
+	c.subscribers[sub] = true
+	// let anybody waiting for us know we are listening
+	c.changed.Broadcast()
+	c.lock.Unlock()
+
+	// we never close the queue, as publish may be sending to it, so events are passed on through a channel we own
+	stream := make(chan message)
+	go func() {
+		defer close(stream)
+		defer func() {
+			c.lock.Lock()
+			delete(c.subscribers, sub)
+			c.lock.Unlock()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-sub.queue:
+				select {
+				case stream <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return stream
+}
+
+// WaitForListener waits until somebody is listening for the events of the container called name.  Events are only
+// sent to the people listening at the time, so a scenario should wait for this before it plays out anything that is
+// decided by an event, such as the container exiting.  An error is returned if ctx is cancelled first.
+func (c *Client) WaitForListener(ctx context.Context, name string) error {
+	// our wait is woken up by changes to the server, so we make sure cancelling ctx counts as one
+	waiting := make(chan struct{})
+	defer close(waiting)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.lock.Lock()
+			c.changed.Broadcast()
+			c.lock.Unlock()
+		case <-waiting:
+		}
+	}()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if state, err := c.find(name); err == nil {
+			for sub := range c.subscribers {
+				// subscribers that have been cancelled are on their way out, and will not see anything new
+				if sub.ctx.Err() == nil && sub.match(state.Container) {
+					return nil
+				}
+			}
+		}
+		c.changed.Wait()
+	}
+}
+
+// ServiceEvents returns a function that streams the events for the containers of service.  It can be used as the
+// Events of a handler.Container in the same way the events of a libcompose project are.
+func (c *Client) ServiceEvents(service string) func(ctx context.Context) (<-chan events.ContainerEvent, error) {
+	return func(ctx context.Context) (<-chan events.ContainerEvent, error) {
+		stream := c.subscribe(ctx, func(container Container) bool {
+			return container.Service == service
+		})
+		containerEvents := make(chan events.ContainerEvent)
+		go func() {
+			defer close(containerEvents)
+			for message := range stream {
+				select {
+				case containerEvents <- message.event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return containerEvents, nil
+	}
+}
+
+// Events implements client.APIClient.Events.  Every container event is sent as json until the stream is closed or
+// ctx is cancelled.  Label filters are applied, as libcompose uses them to pick out the containers of a service, but
+// any other filters are ignored.
+func (c *Client) Events(ctx context.Context, options dockerTypes.EventsOptions) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream := c.subscribe(ctx, func(container Container) bool {
+		return options.Filters.MatchKVList("label", container.Labels)
+	})
+	reader, writer := io.Pipe()
+	go func() {
+		encoder := json.NewEncoder(writer)
+		for message := range stream {
+			// like docker, the labels of the container are sent along with its name and image
+			attributes := map[string]string{"name": message.container.Name, "image": message.container.Image}
+			for key, value := range message.container.Labels {
+				attributes[key] = value
+			}
+			event := message.event
+			err := encoder.Encode(eventtypes.Message{
+				Status: event.Event,
+				ID:     event.ID,
+				Type:   eventtypes.ContainerEventType,
+				Action: event.Event,
+				Actor: eventtypes.Actor{
+					ID:         event.ID,
+					Attributes: attributes,
+				},
+				Time:     event.Time.Unix(),
+				TimeNano: event.Time.UnixNano(),
+			})
+			if err != nil {
+				break
+			}
+		}
+		writer.Close()
+	}()
+	return &eventStream{PipeReader: reader, cancel: cancel}, nil
+}
+
+// eventStream stops sending events once it has been closed
+type eventStream struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+// Close implements io.Closer
+func (s *eventStream) Close() error {
+	s.cancel()
+	return s.PipeReader.Close()
+}
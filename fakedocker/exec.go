@@ -0,0 +1,90 @@
+package fakedocker
+
+import (
+	"fmt"
+
+	dockerTypes "github.com/docker/engine-api/types"
+	"golang.org/x/net/context"
+)
+
+// execState is a command that was run inside a container
+type execState struct {
+	container string
+	command   []string
+	running   bool
+	exitCode  int
+}
+
+// SetExec decides how commands run inside the container exit.  result is given the command and returns its exit
+// code.  Commands exit with 0 until this has been called.
+func (c *Client) SetExec(name string, result func(command []string) int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.mustFind(name).exec = result
+}
+
+// ContainerExecCreate implements client.APIClient.ContainerExecCreate.  Like docker, a command can only be created in a
+// running container.
+func (c *Client) ContainerExecCreate(ctx context.Context, nameOrID string, config dockerTypes.ExecConfig) (dockerTypes.ContainerExecCreateResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	state, err := c.find(nameOrID)
+	if err != nil {
+		return dockerTypes.ContainerExecCreateResponse{}, err
+	}
+	if !state.running {
+		return dockerTypes.ContainerExecCreateResponse{}, fmt.Errorf("Error response from daemon: Container %v is not running", state.ID)
+	}
+
+	id := fmt.Sprintf("exec%v", len(c.execs)+1)
+	c.execs[id] = &execState{container: state.Name, command: config.Cmd}
+	return dockerTypes.ContainerExecCreateResponse{ID: id}, nil
+}
+
+// ContainerExecStart implements client.APIClient.ContainerExecStart.  The command finishes before we return, so it is
+// never seen running.
+func (c *Client) ContainerExecStart(ctx context.Context, execID string, config dockerTypes.ExecStartCheck) error {
+	c.lock.Lock()
+	exec, found := c.execs[execID]
+	if !found {
+		c.lock.Unlock()
+		return fmt.Errorf("Error response from daemon: No such exec instance '%v' found in daemon", execID)
+	}
+	state, err := c.find(exec.container)
+	if err != nil || !state.running {
+		c.lock.Unlock()
+		return fmt.Errorf("Error response from daemon: Container %v is not running", exec.container)
+	}
+	result := state.exec
+	c.lock.Unlock()
+
+	// the scenario decides how the command exits, and it may want to use the client while it does
+	exitCode := 0
+	if result != nil {
+		exitCode = result(exec.command)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	exec.exitCode = exitCode
+	return nil
+}
+
+// ContainerExecInspect implements client.APIClient.ContainerExecInspect
+func (c *Client) ContainerExecInspect(ctx context.Context, execID string) (dockerTypes.ContainerExecInspect, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	exec, found := c.execs[execID]
+	if !found {
+		return dockerTypes.ContainerExecInspect{}, fmt.Errorf("Error response from daemon: No such exec instance '%v' found in daemon", execID)
+	}
+	inspect := dockerTypes.ContainerExecInspect{
+		ExecID:   execID,
+		Running:  exec.running,
+		ExitCode: exec.exitCode,
+	}
+	if state, found := c.containers[exec.container]; found {
+		inspect.ContainerID = state.ID
+	}
+	return inspect, nil
+}
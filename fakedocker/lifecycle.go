@@ -0,0 +1,203 @@
+package fakedocker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	dockerTypes "github.com/docker/engine-api/types"
+	containertypes "github.com/docker/engine-api/types/container"
+	"github.com/docker/engine-api/types/network"
+	"golang.org/x/net/context"
+)
+
+// OnStart has started called with the name of each container started through ContainerStart, once it is running.  It
+// is called on its own goroutine, so it can play out what the container does next for as long as it likes.
+func (c *Client) OnStart(started func(name string)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onStart = started
+}
+
+// imageID makes up the id of image.  Every image is treated as if it had already been pulled.
+func imageID(image string) string {
+	return fmt.Sprintf("sha256:%x", image)
+}
+
+// ImageInspectWithRaw implements client.APIClient.ImageInspectWithRaw.  Every image is treated as if it had already
+// been pulled, so nothing is ever pulled or built.
+func (c *Client) ImageInspectWithRaw(ctx context.Context, image string, getSize bool) (dockerTypes.ImageInspect, []byte, error) {
+	return dockerTypes.ImageInspect{ID: imageID(image), RepoTags: []string{image}}, nil, nil
+}
+
+// ContainerCreate implements client.APIClient.ContainerCreate.  The container is stopped until it is started with
+// ContainerStart.  Its binds are turned into mounts the way docker does, but nothing is done with them.
+func (c *Client) ContainerCreate(ctx context.Context, config *containertypes.Config, hostConfig *containertypes.HostConfig, networkingConfig *network.NetworkingConfig, containerName string) (dockerTypes.ContainerCreateResponse, error) {
+	c.lock.Lock()
+	if _, found := c.containers[containerName]; found {
+		c.lock.Unlock()
+		return dockerTypes.ContainerCreateResponse{}, fmt.Errorf("Error response from daemon: Conflict. The name \"/%v\" is already in use by container %v", containerName, c.containers[containerName].ID)
+	}
+
+	// libcompose names containers after part of their id when it recreates them, so they need to be as long as docker's
+	c.created++
+	container := Container{
+		Name:   containerName,
+		ID:     fmt.Sprintf("%064x", c.created),
+		Labels: make(map[string]string),
+		Image:  config.Image,
+	}
+	for key, value := range config.Labels {
+		container.Labels[key] = value
+	}
+	container.Service = container.Labels["com.docker.compose.service"]
+	if hostConfig != nil {
+		for _, bind := range hostConfig.Binds {
+			container.Mounts = append(container.Mounts, mount(bind))
+		}
+	}
+	c.order = append(c.order, containerName)
+	c.containers[containerName] = &state{Container: container, networks: make(map[string]*network.EndpointSettings)}
+	c.lock.Unlock()
+
+	c.publish(container, "create")
+	return dockerTypes.ContainerCreateResponse{ID: container.ID}, nil
+}
+
+// mount describes the volume docker mounts for bind, which is in the form SOURCE:DESTINATION[:MODE].  A source that
+// is not a path is a named volume.
+func mount(bind string) dockerTypes.MountPoint {
+	parts := strings.SplitN(bind, ":", 3)
+	mountPoint := dockerTypes.MountPoint{Source: parts[0], Destination: parts[0], RW: true}
+	if len(parts) > 1 {
+		mountPoint.Destination = parts[1]
+	}
+	if len(parts) > 2 {
+		mountPoint.Mode = parts[2]
+		mountPoint.RW = !strings.Contains(parts[2], "ro")
+	}
+	if !strings.HasPrefix(mountPoint.Source, "/") {
+		mountPoint.Name = mountPoint.Source
+		mountPoint.Driver = "local"
+		mountPoint.Source = fmt.Sprintf("/var/lib/docker/volumes/%v/_data", mountPoint.Name)
+	}
+	return mountPoint
+}
+
+// ContainerStart implements client.APIClient.ContainerStart.  Starting a container that is already running does
+// nothing, as it does in docker.
+func (c *Client) ContainerStart(ctx context.Context, nameOrID string, options dockerTypes.ContainerStartOptions) error {
+	c.lock.Lock()
+	state, err := c.find(nameOrID)
+	if err != nil {
+		c.lock.Unlock()
+		return err
+	}
+	if state.running {
+		c.lock.Unlock()
+		return nil
+	}
+	state.running = true
+	state.exitCode = 0
+	container := state.Container
+	started := c.onStart
+	c.lock.Unlock()
+
+	c.publish(container, "start")
+	if started != nil {
+		go started(container.Name)
+	}
+	return nil
+}
+
+// ContainerStop implements client.APIClient.ContainerStop.  Containers exit straight away with the 143 of a process
+// that was stopped by SIGTERM, so timeout is never reached.
+func (c *Client) ContainerStop(ctx context.Context, nameOrID string, timeout *time.Duration) error {
+	return c.signal(nameOrID, "stop", 143)
+}
+
+// ContainerKill implements client.APIClient.ContainerKill.  Containers exit straight away with the 137 of a process
+// that was killed, whatever signal is sent.
+func (c *Client) ContainerKill(ctx context.Context, nameOrID string, signal string) error {
+	return c.signal(nameOrID, "kill", 137)
+}
+
+// signal stops a running container with code, and sends a die event along with event for it.  A container that is
+// not running is left alone.
+func (c *Client) signal(nameOrID string, event string, code int) error {
+	c.lock.Lock()
+	state, err := c.find(nameOrID)
+	if err != nil {
+		c.lock.Unlock()
+		return err
+	}
+	if !state.running {
+		c.lock.Unlock()
+		return nil
+	}
+	c.stop(state, code)
+	container := state.Container
+	c.lock.Unlock()
+
+	c.publish(container, "die")
+	c.publish(container, event)
+	return nil
+}
+
+// ContainerRename implements client.APIClient.ContainerRename
+func (c *Client) ContainerRename(ctx context.Context, nameOrID string, newContainerName string) error {
+	c.lock.Lock()
+	state, err := c.find(nameOrID)
+	if err != nil {
+		c.lock.Unlock()
+		return err
+	}
+	if _, found := c.containers[newContainerName]; found {
+		c.lock.Unlock()
+		return fmt.Errorf("Error response from daemon: Conflict. The name \"/%v\" is already in use", newContainerName)
+	}
+	delete(c.containers, state.Name)
+	for index, name := range c.order {
+		if name == state.Name {
+			c.order[index] = newContainerName
+		}
+	}
+	state.Name = newContainerName
+	c.containers[newContainerName] = state
+	container := state.Container
+	c.lock.Unlock()
+
+	c.publish(container, "rename")
+	return nil
+}
+
+// NetworkConnect implements client.APIClient.NetworkConnect.  Networks do not need to be created before containers
+// are connected to them.
+func (c *Client) NetworkConnect(ctx context.Context, networkID, nameOrID string, config *network.EndpointSettings) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	state, err := c.find(nameOrID)
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		config = &network.EndpointSettings{}
+	}
+	state.networks[networkID] = config
+	return nil
+}
+
+// NetworkDisconnect implements client.APIClient.NetworkDisconnect
+func (c *Client) NetworkDisconnect(ctx context.Context, networkID, nameOrID string, force bool) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	state, err := c.find(nameOrID)
+	if err != nil {
+		return err
+	}
+	if _, found := state.networks[networkID]; !found && !force {
+		return fmt.Errorf("Error response from daemon: container %v is not connected to the network %v", state.ID, networkID)
+	}
+	delete(state.networks, networkID)
+	return nil
+}
@@ -0,0 +1,130 @@
+package fakedocker
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	dockerTypes "github.com/docker/engine-api/types"
+	timetypes "github.com/docker/engine-api/types/time"
+	"golang.org/x/net/context"
+)
+
+// ContainerLogs implements client.APIClient.ContainerLogs.  Like docker, STDOUT and STDERR are sent down the same
+// stream with stdcopy headers.  If we are following, the stream ends once the container stops, the stream is closed
+// or ctx is cancelled.
+func (c *Client) ContainerLogs(ctx context.Context, nameOrID string, options dockerTypes.ContainerLogsOptions) (io.ReadCloser, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	state, err := c.find(nameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &logStream{
+		client: c,
+		state:  state,
+		stdout: options.ShowStdout,
+		stderr: options.ShowStderr,
+		follow: options.Follow,
+		done:   make(chan struct{}),
+	}
+	// we can be asked for only the output written since a point in time, given in any of the forms docker accepts
+	if options.Since != "" {
+		timestamp, err := timetypes.GetTimestamp(options.Since, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		seconds, nanoseconds, err := timetypes.ParseTimestamps(timestamp, 0)
+		if err != nil {
+			return nil, err
+		}
+		stream.since = time.Unix(seconds, nanoseconds)
+	}
+	// work out where to start if we only want the end of the output
+	if tail, err := strconv.Atoi(options.Tail); err == nil {
+		stream.next = len(state.lines)
+		for stream.next > 0 && tail > 0 {
+			stream.next--
+			if stream.wants(state.lines[stream.next]) {
+				tail--
+			}
+		}
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			stream.Close()
+		case <-stream.done:
+		}
+	}()
+	return stream, nil
+}
+
+// logStream reads the output of a container, and waits for more if it is following it
+type logStream struct {
+	client *Client
+	state  *state
+	stdout bool
+	stderr bool
+	follow bool
+	// since leaves out the lines written before it
+	since time.Time
+	// next is the index of the next line of the container we have not looked at yet
+	next   int
+	buffer bytes.Buffer
+	closed bool
+	done   chan struct{}
+}
+
+// wants returns true if line comes from a stream we were asked for, and was written when we were asked for
+func (s *logStream) wants(line logLine) bool {
+	if line.written.Before(s.since) {
+		return false
+	}
+	return (line.stderr && s.stderr) || (!line.stderr && s.stdout)
+}
+
+// Read implements io.Reader
+func (s *logStream) Read(p []byte) (int, error) {
+	s.client.lock.Lock()
+	defer s.client.lock.Unlock()
+	for s.buffer.Len() == 0 {
+		if s.closed {
+			return 0, io.EOF
+		}
+		if s.next < len(s.state.lines) {
+			line := s.state.lines[s.next]
+			s.next++
+			if !s.wants(line) {
+				continue
+			}
+			streamType := stdcopy.Stdout
+			if line.stderr {
+				streamType = stdcopy.Stderr
+			}
+			stdcopy.NewStdWriter(&s.buffer, streamType).Write([]byte(line.text + "\n"))
+			continue
+		}
+		if !s.follow || !s.state.running {
+			return 0, io.EOF
+		}
+		s.client.changed.Wait()
+	}
+	return s.buffer.Read(p)
+}
+
+// Close implements io.Closer
+func (s *logStream) Close() error {
+	s.client.lock.Lock()
+	defer s.client.lock.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+		s.client.changed.Broadcast()
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+module github.com/dansteen/controlled-compose
+
+go 1.16
+
+require (
+	github.com/BurntSushi/toml v0.3.0
+	github.com/Sirupsen/logrus v1.0.0
+	github.com/cloudfoundry-incubator/candiedyaml v0.0.0-20160429080125-99c3df83b515
+	github.com/docker/distribution v2.5.1+incompatible
+	github.com/docker/docker v1.12.0
+	github.com/docker/engine-api v0.3.2-0.20160708123604-98348ad6f9c8
+	github.com/docker/go-connections v0.2.1
+	github.com/docker/go-units v0.3.3
+	github.com/docker/libcompose v0.3.0
+	github.com/flynn/go-shlex v0.0.0-20150515145356-3f9db97f8568
+	github.com/fsnotify/fsnotify v1.4.9
+	github.com/gorilla/context v1.1.2
+	github.com/gorilla/mux v1.7.0
+	github.com/hashicorp/hcl v1.0.0
+	github.com/hpcloud/tail v1.0.0
+	github.com/imdario/mergo v0.3.5
+	github.com/magiconair/properties v1.7.6
+	github.com/mitchellh/mapstructure v1.0.0
+	github.com/opencontainers/runc v0.1.1
+	github.com/spf13/cast v1.2.0
+	github.com/spf13/cobra v0.0.3
+	github.com/spf13/jwalterweatherman v0.0.0-20141219030609-3d60171a6431
+	github.com/spf13/pflag v1.0.5
+	github.com/spf13/viper v0.0.0-20160605220307-c1ccc378a054
+	github.com/twmb/algoimpl v0.0.0-20170717182524-076353e90b94
+	github.com/vbatts/tar-split v0.10.2
+	github.com/vdemeester/docker-events be74d4929ec1ad118df54349fda4b0cba60f849b
+	github.com/xeipuuv/gojsonpointer v0.0.0-20151027082146-e0fe6f683076
+	github.com/xeipuuv/gojsonreference v0.0.0-20150808065054-e02fc20de94c
+	github.com/xeipuuv/gojsonschema v0.0.0-20161231055540-f06f290571ce
+	golang.org/x/crypto v0.21.0
+	golang.org/x/net v0.21.0
+	golang.org/x/sys v0.18.0
+	golang.org/x/term v0.18.0
+	gopkg.in/fsnotify.v1 v1.4.7
+	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7
+	gopkg.in/yaml.v2 v2.4.0
+)
@@ -0,0 +1,243 @@
+// Handler provides various state hanlders for our controlled compose run
+package handler
+
+import (
+	"fmt"
+	"github.com/dansteen/controlled-compose/types"
+	"github.com/docker/engine-api/client"
+	"github.com/docker/libcompose/project/events"
+	"golang.org/x/net/context"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Container holds the information our handlers need about the container they are monitoring
+type Container struct {
+	Client client.APIClient
+	Name   string
+	// Events returns a new stream of docker events for the service the container belongs to, which ends once ctx
+	// is cancelled
+	Events func(ctx context.Context) (<-chan events.ContainerEvent, error)
+	// Out is where our handlers write their progress messages.  Nothing is written if it is nil
+	Out io.Writer
+	// Since is when the sequence step being evaluated started.  Output written before it belongs to an earlier step,
+	// so it is not looked at again.  Everything the container has written is looked at if it is zero
+	Since time.Time
+}
+
+// out returns where our handlers should write their progress messages
+func (c Container) out() io.Writer {
+	if c.Out == nil {
+		return ioutil.Discard
+	}
+	return c.Out
+}
+
+// Evaluate runs the handlers for a set of state conditions against a container and reports the first status that
+// decides them.  Conditions in the same set race each other, and all_of, any_of and sequence groups each take part
+// in that race with the single status they report once they have been decided.  Everything Evaluate starts is
+// stopped once it returns or ctx is cancelled.
+func Evaluate(ctx context.Context, container Container, conditions types.StateConditions, container_status chan<- types.ContainerStatus) {
+	// the return status of our handlers
+	responses := make(chan types.ContainerStatus)
+	// our handlers are cancelled as soon as we no longer need them
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	started := 0
+	// start each of our registered conditions
+	for _, key := range sortedConditions(conditions.Conditions) {
+		condition, ok := conditions.Conditions[key].(Condition)
+		if !ok {
+			reportError(ctx, container_status, key, fmt.Errorf("The %v condition was not registered with RegisterCondition and can not be run", key))
+			return
+		}
+		go condition.Run(ctx, container, responses)
+		started++
+	}
+
+	// then our groups
+	if len(conditions.AllOf) > 0 {
+		go allOf(ctx, container, conditions.AllOf, responses)
+		started++
+	}
+	if len(conditions.AnyOf) > 0 {
+		go anyOf(ctx, container, conditions.AnyOf, responses)
+		started++
+	}
+	if len(conditions.Sequence) > 0 {
+		go sequence(ctx, container, conditions.Sequence, responses)
+		started++
+	}
+
+	// an empty set of conditions has nothing to wait on
+	if started == 0 {
+		report(ctx, container_status, types.ContainerStatus{
+			Status:  "success",
+			Message: "No conditions to wait for.",
+		})
+		return
+	}
+
+	// wait for the first response
+	select {
+	case response := <-responses:
+		report(ctx, container_status, response)
+	case <-ctx.Done():
+		return
+	}
+}
+
+// allOf succeeds once every group has succeeded, and fails as soon as any of them fails
+func allOf(ctx context.Context, container Container, groups []types.StateConditions, container_status chan<- types.ContainerStatus) {
+	responses := make(chan types.ContainerStatus)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for _, group := range groups {
+		go Evaluate(ctx, container, group, responses)
+	}
+
+	messages := make([]string, 0)
+	for range groups {
+		select {
+		case response := <-responses:
+			if response.Status != "success" {
+				report(ctx, container_status, types.ContainerStatus{
+					Status:    response.Status,
+					Condition: fmt.Sprintf("all_of > %v", response.Condition),
+					Message:   fmt.Sprintf("all_of failed: %v", strings.TrimSpace(response.Message)),
+					Err:       response.Err,
+				})
+				return
+			}
+			messages = append(messages, strings.TrimSpace(response.Message))
+		case <-ctx.Done():
+			return
+		}
+	}
+	report(ctx, container_status, types.ContainerStatus{
+		Status:    "success",
+		Condition: "all_of",
+		Message:   fmt.Sprintf("all_of succeeded: %v", strings.Join(messages, "; ")),
+	})
+}
+
+// anyOf succeeds as soon as any group succeeds, and fails once every group has failed
+func anyOf(ctx context.Context, container Container, groups []types.StateConditions, container_status chan<- types.ContainerStatus) {
+	responses := make(chan types.ContainerStatus)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for _, group := range groups {
+		go Evaluate(ctx, container, group, responses)
+	}
+
+	messages := make([]string, 0)
+	failed := make([]string, 0)
+	for range groups {
+		select {
+		case response := <-responses:
+			if response.Status == "success" {
+				report(ctx, container_status, types.ContainerStatus{
+					Status:    "success",
+					Condition: fmt.Sprintf("any_of > %v", response.Condition),
+					Message:   fmt.Sprintf("any_of succeeded: %v", strings.TrimSpace(response.Message)),
+				})
+				return
+			}
+			// an error means we can not tell how the group would have turned out, so we pass it straight on
+			if response.Status == "error" {
+				report(ctx, container_status, types.ContainerStatus{
+					Status:    "error",
+					Condition: fmt.Sprintf("any_of > %v", response.Condition),
+					Message:   fmt.Sprintf("any_of failed: %v", strings.TrimSpace(response.Message)),
+					Err:       response.Err,
+				})
+				return
+			}
+			messages = append(messages, strings.TrimSpace(response.Message))
+			failed = append(failed, response.Condition)
+		case <-ctx.Done():
+			return
+		}
+	}
+	// every group had to fail for us to get here, so we name all of the conditions that decided them
+	report(ctx, container_status, types.ContainerStatus{
+		Status:    "failure",
+		Condition: fmt.Sprintf("any_of > %v", strings.Join(failed, " | ")),
+		Message:   fmt.Sprintf("any_of failed: %v", strings.Join(messages, "; ")),
+	})
+}
+
+// sequence evaluates each group only once the one before it has succeeded, and fails as soon as any of them fails.
+// Each step after the first only looks at the output written since it started, so it can not be decided by output
+// an earlier step has already seen.
+func sequence(ctx context.Context, container Container, groups []types.StateConditions, container_status chan<- types.ContainerStatus) {
+	messages := make([]string, 0)
+	for index, group := range groups {
+		responses := make(chan types.ContainerStatus)
+		step, cancel := context.WithCancel(ctx)
+		step_container := container
+		if index > 0 {
+			step_container.Since = time.Now()
+		}
+		go Evaluate(step, step_container, group, responses)
+
+		var response types.ContainerStatus
+		select {
+		case response = <-responses:
+			cancel()
+		case <-ctx.Done():
+			cancel()
+			return
+		}
+
+		if response.Status != "success" {
+			report(ctx, container_status, types.ContainerStatus{
+				Status:    response.Status,
+				Condition: fmt.Sprintf("sequence > %v", response.Condition),
+				Message:   fmt.Sprintf("sequence failed at step %v: %v", index+1, strings.TrimSpace(response.Message)),
+				Err:       response.Err,
+			})
+			return
+		}
+		messages = append(messages, strings.TrimSpace(response.Message))
+	}
+	report(ctx, container_status, types.ContainerStatus{
+		Status:    "success",
+		Condition: "sequence",
+		Message:   fmt.Sprintf("sequence succeeded: %v", strings.Join(messages, "; ")),
+	})
+}
+
+// sortedConditions returns the keys of conditions in a stable order
+func sortedConditions(conditions map[string]types.Condition) []string {
+	keys := make([]string, 0, len(conditions))
+	for key := range conditions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reportError lets our caller know that condition could not be decided because of err
+func reportError(ctx context.Context, container_status chan<- types.ContainerStatus, condition string, err error) {
+	report(ctx, container_status, types.ContainerStatus{
+		Status:    "error",
+		Condition: condition,
+		Message:   err.Error(),
+		Err:       err,
+	})
+}
+
+// report sends status back to our caller unless ctx has been cancelled, in which case nobody is listening anymore
+func report(ctx context.Context, container_status chan<- types.ContainerStatus, status types.ContainerStatus) {
+	select {
+	case container_status <- status:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,334 @@
+// Handler provides various state hanlders for our controlled compose run
+package handler
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FieldError describes a value in the config of a service that is not what we expected
+type FieldError struct {
+	Service string
+	Field   string
+	Message string
+}
+
+// Error implements the error interface
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("service %v: %v: %v", e.Service, e.Field, e.Message)
+}
+
+// Fields gives typed access to a map read in by the yaml parser.  Each getter leaves its target alone if the key is
+// not present, and returns a FieldError naming the service and key path if the value is not of the expected type.
+// Every key we are asked for is remembered, so Unknown can report the keys nobody asked for.
+type Fields struct {
+	service string
+	path    string
+	values  map[interface{}]interface{}
+	// read is shared by every copy of our Fields
+	read map[string]bool
+}
+
+// NewFields wraps value, which should be a map found at path in the config of service
+func NewFields(service string, path string, value interface{}) (Fields, error) {
+	values, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return Fields{}, &FieldError{Service: service, Field: path, Message: fmt.Sprintf("expected a map but found %v", Describe(value))}
+	}
+	return Fields{service: service, path: path, values: values, read: make(map[string]bool)}, nil
+}
+
+// Unknown returns an error for a key that none of our getters were asked for, which is usually a typo such as
+// "stauts".  Parsers call it once they have read everything they know about.
+func (f Fields) Unknown() error {
+	known := make([]string, 0, len(f.read))
+	for key := range f.read {
+		known = append(known, key)
+	}
+	sort.Strings(known)
+	unknown := make([]string, 0)
+	for key := range f.values {
+		if !f.wasRead(key) {
+			unknown = append(unknown, fmt.Sprintf("%v", key))
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return f.Fail(unknown[0], "unknown key, expected one of %v", strings.Join(known, ", "))
+}
+
+// yaml 1.1 reads some words as booleans even when they are used as keys, so an unquoted "on" (as in retry.on) is
+// found under true
+var booleanKeys = map[string]bool{"on": true, "off": false, "yes": true, "no": false}
+
+// get returns the raw value found at key, and remembers that we were asked for it
+func (f Fields) get(key string) (interface{}, bool) {
+	f.read[key] = true
+	if value, found := f.values[key]; found {
+		return value, true
+	}
+	if boolean, ok := booleanKeys[key]; ok {
+		value, found := f.values[boolean]
+		return value, found
+	}
+	return nil, false
+}
+
+// wasRead returns true if one of our getters was asked for key, which may be a boolean that stands in for the word
+// it was read from
+func (f Fields) wasRead(key interface{}) bool {
+	switch key := key.(type) {
+	case string:
+		return f.read[key]
+	case bool:
+		for name, boolean := range booleanKeys {
+			if boolean == key && f.read[name] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Path returns the key path to our map
+func (f Fields) Path() string {
+	return f.path
+}
+
+// Field returns the key path to key
+func (f Fields) Field(key string) string {
+	return fmt.Sprintf("%v.%v", f.path, key)
+}
+
+// Value returns the raw value found at key, or nil if it is not present
+func (f Fields) Value(key string) interface{} {
+	value, _ := f.get(key)
+	return value
+}
+
+// Fail builds an error for key
+func (f Fields) Fail(key string, format string, args ...interface{}) error {
+	return &FieldError{Service: f.service, Field: f.Field(key), Message: fmt.Sprintf(format, args...)}
+}
+
+// Has returns true if key is present
+func (f Fields) Has(key string) bool {
+	_, found := f.get(key)
+	return found
+}
+
+// Map returns the map found at key
+func (f Fields) Map(key string) (Fields, bool, error) {
+	value, found := f.get(key)
+	if !found {
+		return Fields{}, false, nil
+	}
+	child, err := NewFields(f.service, f.Field(key), value)
+	return child, true, err
+}
+
+// List returns the list found at key
+func (f Fields) List(key string) ([]interface{}, bool, error) {
+	value, found := f.get(key)
+	if !found {
+		return nil, false, nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, true, f.Fail(key, "expected a list but found %v", Describe(value))
+	}
+	return list, true, nil
+}
+
+// String reads a string
+func (f Fields) String(key string, target *string) error {
+	value, found := f.get(key)
+	if !found {
+		return nil
+	}
+	str, ok := value.(string)
+	if !ok {
+		return f.Fail(key, "expected a string but found %v", Describe(value))
+	}
+	*target = str
+	return nil
+}
+
+// Int reads a whole number
+func (f Fields) Int(key string, target *int) error {
+	value, found := f.get(key)
+	if !found {
+		return nil
+	}
+	number, ok := wholeNumber(value)
+	if !ok {
+		return f.Fail(key, "expected a whole number but found %v", Describe(value))
+	}
+	*target = number
+	return nil
+}
+
+// Float reads any number
+func (f Fields) Float(key string, target *float64) error {
+	value, found := f.get(key)
+	if !found {
+		return nil
+	}
+	if number, ok := wholeNumber(value); ok {
+		*target = float64(number)
+		return nil
+	}
+	number, ok := value.(float64)
+	if !ok {
+		return f.Fail(key, "expected a number but found %v", Describe(value))
+	}
+	*target = number
+	return nil
+}
+
+// Bool reads true or false
+func (f Fields) Bool(key string, target *bool) error {
+	value, found := f.get(key)
+	if !found {
+		return nil
+	}
+	boolean, ok := value.(bool)
+	if !ok {
+		return f.Fail(key, "expected true or false but found %v", Describe(value))
+	}
+	*target = boolean
+	return nil
+}
+
+// Ints reads a list of whole numbers
+func (f Fields) Ints(key string, target *[]int) error {
+	list, found, err := f.List(key)
+	if !found || err != nil {
+		return err
+	}
+	numbers := make([]int, 0, len(list))
+	for index, value := range list {
+		number, ok := wholeNumber(value)
+		if !ok {
+			return f.Fail(fmt.Sprintf("%v[%v]", key, index), "expected a whole number but found %v", Describe(value))
+		}
+		numbers = append(numbers, number)
+	}
+	*target = numbers
+	return nil
+}
+
+// Strings reads a list of strings
+func (f Fields) Strings(key string, target *[]string) error {
+	list, found, err := f.List(key)
+	if !found || err != nil {
+		return err
+	}
+	values := make([]string, 0, len(list))
+	for index, value := range list {
+		str, ok := value.(string)
+		if !ok {
+			return f.Fail(fmt.Sprintf("%v[%v]", key, index), "expected a string but found %v", Describe(value))
+		}
+		values = append(values, str)
+	}
+	*target = values
+	return nil
+}
+
+// Regex reads and compiles a regular expression
+func (f Fields) Regex(key string, target **regexp.Regexp) error {
+	var expression string
+	if err := f.String(key, &expression); err != nil || !f.Has(key) {
+		return err
+	}
+	regex, err := regexp.Compile(expression)
+	if err != nil {
+		return f.Fail(key, "invalid regular expression: %v", err)
+	}
+	*target = regex
+	return nil
+}
+
+// Status reads a status, which must be either success or failure
+func (f Fields) Status(key string, target *string) error {
+	var status string
+	if err := f.String(key, &status); err != nil || !f.Has(key) {
+		return err
+	}
+	if status != "success" && status != "failure" {
+		return f.Fail(key, "expected success or failure but found %#v", status)
+	}
+	*target = status
+	return nil
+}
+
+// Duration reads a duration.  Numbers (whole or fractional) are treated as seconds, and strings are parsed as go
+// durations such as "1m30s"
+func (f Fields) Duration(key string, target *time.Duration) error {
+	value, found := f.get(key)
+	if !found {
+		return nil
+	}
+	if seconds, ok := wholeNumber(value); ok {
+		value = float64(seconds)
+	}
+	switch duration := value.(type) {
+	case float64:
+		*target = time.Duration(duration * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(duration)
+		if err != nil {
+			return f.Fail(key, "invalid duration: %v", err)
+		}
+		*target = parsed
+	default:
+		return f.Fail(key, "expected a number of seconds or a duration such as 1m30s but found %v", Describe(value))
+	}
+	if *target <= 0 {
+		return f.Fail(key, "expected a duration greater than 0")
+	}
+	return nil
+}
+
+// Require returns an error if any of keys are not present
+func (f Fields) Require(keys ...string) error {
+	for _, key := range keys {
+		if !f.Has(key) {
+			return f.Fail(key, "missing required key")
+		}
+	}
+	return nil
+}
+
+// wholeNumber returns value as an int if it is a whole number.  The yaml parsers used by us and by libcompose read
+// whole numbers as int64 and int respectively, so we accept both.
+func wholeNumber(value interface{}) (int, bool) {
+	switch number := value.(type) {
+	case int:
+		return number, true
+	case int64:
+		return int(number), true
+	}
+	return 0, false
+}
+
+// Describe formats a value read in by the yaml parser for use in an error message
+func Describe(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "nothing"
+	case map[interface{}]interface{}:
+		return "a map"
+	case []interface{}:
+		return "a list"
+	case float64:
+		return fmt.Sprintf("the decimal %v", value)
+	}
+	return fmt.Sprintf("%#v", value)
+}
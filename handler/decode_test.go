@@ -0,0 +1,136 @@
+package handler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dansteen/controlled-compose/handler"
+)
+
+// parse runs the parser registered for key against value
+func parse(t *testing.T, key string, value interface{}) error {
+	parser, found := handler.LookupCondition(key)
+	if !found {
+		t.Fatalf("no condition is registered for %v", key)
+	}
+	_, err := parser(handler.ParseContext{
+		Service:   "web",
+		Path:      "state_conditions." + key,
+		ExportDir: func(dir string) (string, error) { return dir, nil },
+	}, value)
+	return err
+}
+
+var unknownKeyTests = []struct {
+	name  string
+	key   string
+	value interface{}
+	// the field we expect to be reported, or nothing if the config is fine
+	field string
+}{
+	{
+		name:  "http with known keys",
+		key:   "http",
+		value: map[interface{}]interface{}{"port": 80, "status": "failure"},
+	},
+	{
+		name:  "http with a misspelled key",
+		key:   "http",
+		value: map[interface{}]interface{}{"port": 80, "stauts": "failure"},
+		field: "state_conditions.http.stauts",
+	},
+	{
+		name:  "tcp with a misspelled key",
+		key:   "tcp",
+		value: map[interface{}]interface{}{"port": 80, "intreval": 2},
+		field: "state_conditions.tcp.intreval",
+	},
+	{
+		name:  "exec with a misspelled key",
+		key:   "exec",
+		value: map[interface{}]interface{}{"command": "true", "retires": 3},
+		field: "state_conditions.exec.retires",
+	},
+	{
+		name:  "timeout with a key that is not a string",
+		key:   "timeout",
+		value: map[interface{}]interface{}{"duration": 5, "status": "failure", 10: "seconds"},
+		field: "state_conditions.timeout.10",
+	},
+	{
+		name: "filemonitor with a misspelled key in its second monitor",
+		key:  "filemonitor",
+		value: []interface{}{
+			map[interface{}]interface{}{"file": "STDOUT", "regex": "ready", "status": "success"},
+			map[interface{}]interface{}{"file": "STDERR", "regex": "error", "status": "failure", "rgex": "panic"},
+		},
+		field: "state_conditions.filemonitor[1].rgex",
+	},
+}
+
+func TestUnknownKeys(t *testing.T) {
+	for _, test := range unknownKeyTests {
+		t.Run(test.name, func(t *testing.T) {
+			err := parse(t, test.key, test.value)
+			if test.field == "" {
+				if err != nil {
+					t.Errorf("expected no error but got %v", err)
+				}
+				return
+			}
+			fieldErr, ok := err.(*handler.FieldError)
+			if !ok {
+				t.Fatalf("expected a *handler.FieldError for %v but got %T: %v", test.field, err, err)
+			}
+			if fieldErr.Field != test.field {
+				t.Errorf("expected %v to be reported but got %v", test.field, fieldErr)
+			}
+		})
+	}
+}
+
+var durationTests = []struct {
+	name     string
+	value    interface{}
+	duration time.Duration
+	// whether we expect the value to be rejected
+	err bool
+}{
+	{name: "a whole number of seconds as yaml.v2 reads it", value: 5, duration: 5 * time.Second},
+	{name: "a whole number of seconds as candiedyaml reads it", value: int64(90), duration: 90 * time.Second},
+	{name: "a fractional number of seconds", value: 0.25, duration: 250 * time.Millisecond},
+	{name: "a go duration", value: "1m30s", duration: 90 * time.Second},
+	{name: "a go duration in milliseconds", value: "200ms", duration: 200 * time.Millisecond},
+	{name: "a duration without a unit", value: "90", err: true},
+	{name: "a duration that is not one", value: "soon", err: true},
+	{name: "zero", value: 0, err: true},
+	{name: "a negative duration", value: "-1s", err: true},
+	{name: "a list", value: []interface{}{5}, err: true},
+	{name: "a boolean", value: true, err: true},
+}
+
+func TestDuration(t *testing.T) {
+	for _, test := range durationTests {
+		t.Run(test.name, func(t *testing.T) {
+			fields, err := handler.NewFields("web", "state_conditions.timeout", map[interface{}]interface{}{"duration": test.value})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var duration time.Duration
+			err = fields.Duration("duration", &duration)
+			if test.err {
+				fieldErr, ok := err.(*handler.FieldError)
+				if !ok || fieldErr.Field != "state_conditions.timeout.duration" {
+					t.Errorf("expected state_conditions.timeout.duration to be reported but got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if duration != test.duration {
+				t.Errorf("expected %v but got %v", test.duration, duration)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package handler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dansteen/controlled-compose/fakedocker"
+	"github.com/dansteen/controlled-compose/handler"
+	"github.com/dansteen/controlled-compose/types"
+	"golang.org/x/net/context"
+)
+
+// scenario is a container going through its start up while Evaluate decides it
+type scenario struct {
+	name string
+	// config is the state_conditions of the service as they are read from the compose file
+	config map[interface{}]interface{}
+	// setup prepares the container before Evaluate starts, and run plays out the scenario once it has
+	setup func(fake *fakedocker.Client)
+	run   func(fake *fakedocker.Client)
+	// the status we expect Evaluate to report, and the condition that should decide it
+	status    string
+	condition string
+}
+
+var scenarios = []scenario{
+	{
+		name:      "exit with an expected exit code",
+		config:    map[interface{}]interface{}{"exit": []interface{}{int64(0)}},
+		run:       func(fake *fakedocker.Client) { fake.Exit("db", 0) },
+		status:    "success",
+		condition: "exit",
+	},
+	{
+		name:      "exit with an unexpected exit code",
+		config:    map[interface{}]interface{}{"exit": []interface{}{int64(0)}},
+		run:       func(fake *fakedocker.Client) { fake.Exit("db", 3) },
+		status:    "failure",
+		condition: "exit",
+	},
+	{
+		name:      "exit when expected to persist",
+		config:    map[interface{}]interface{}{"exit": []interface{}{int64(-1)}},
+		run:       func(fake *fakedocker.Client) { fake.Exit("db", 0) },
+		status:    "failure",
+		condition: "exit",
+	},
+	{
+		name: "persist until the timeout",
+		config: map[interface{}]interface{}{
+			"exit":    []interface{}{int64(-1)},
+			"timeout": map[interface{}]interface{}{"duration": "200ms", "status": "success"},
+		},
+		status:    "success",
+		condition: "timeout",
+	},
+	{
+		name: "timeout before the output is seen",
+		config: map[interface{}]interface{}{
+			"timeout": map[interface{}]interface{}{"duration": "200ms", "status": "failure"},
+			"filemonitor": []interface{}{
+				map[interface{}]interface{}{"file": "STDOUT", "regex": "ready to accept connections", "status": "success"},
+			},
+		},
+		run:       func(fake *fakedocker.Client) { fake.Stdout("db", "starting up") },
+		status:    "failure",
+		condition: "timeout",
+	},
+	{
+		name: "output matches a filemonitor regex",
+		config: map[interface{}]interface{}{
+			"timeout": map[interface{}]interface{}{"duration": "5s", "status": "failure"},
+			"filemonitor": []interface{}{
+				map[interface{}]interface{}{"file": "STDOUT", "regex": "ready to accept connections", "status": "success"},
+			},
+		},
+		run: func(fake *fakedocker.Client) {
+			fake.Stdout("db", "starting up", "database system is ready to accept connections")
+		},
+		status:    "success",
+		condition: "filemonitor STDOUT",
+	},
+	{
+		name: "output on the wrong stream does not match",
+		config: map[interface{}]interface{}{
+			"timeout": map[interface{}]interface{}{"duration": "200ms", "status": "failure"},
+			"filemonitor": []interface{}{
+				map[interface{}]interface{}{"file": "STDOUT", "regex": "ready to accept connections", "status": "success"},
+			},
+		},
+		run:       func(fake *fakedocker.Client) { fake.Stderr("db", "database system is ready to accept connections") },
+		status:    "failure",
+		condition: "timeout",
+	},
+	{
+		name: "exec exits with an expected exit code",
+		config: map[interface{}]interface{}{
+			"exec": map[interface{}]interface{}{"command": "pg_isready", "interval": 0.05},
+		},
+		status:    "success",
+		condition: "exec",
+	},
+	{
+		name: "exec runs out of retries",
+		config: map[interface{}]interface{}{
+			"exec": map[interface{}]interface{}{"command": "pg_isready", "interval": 0.05, "retries": int64(2)},
+		},
+		setup:     func(fake *fakedocker.Client) { fake.SetExec("db", func(command []string) int { return 1 }) },
+		status:    "failure",
+		condition: "exec",
+	},
+	{
+		name: "exec in a container that has exited",
+		config: map[interface{}]interface{}{
+			"exec": map[interface{}]interface{}{"command": "pg_isready", "interval": 0.05, "retries": int64(2)},
+		},
+		setup:     func(fake *fakedocker.Client) { fake.Exit("db", 1) },
+		status:    "failure",
+		condition: "exec",
+	},
+	{
+		name: "a sequence step does not look at output an earlier step has seen",
+		config: map[interface{}]interface{}{
+			"sequence": []interface{}{
+				map[interface{}]interface{}{
+					"filemonitor": []interface{}{
+						map[interface{}]interface{}{"file": "STDOUT", "regex": "starting", "status": "success"},
+					},
+				},
+				map[interface{}]interface{}{
+					"timeout": map[interface{}]interface{}{"duration": "200ms", "status": "failure"},
+					"filemonitor": []interface{}{
+						map[interface{}]interface{}{"file": "STDOUT", "regex": "ready", "status": "success"},
+					},
+				},
+			},
+		},
+		run:       func(fake *fakedocker.Client) { fake.Stdout("db", "starting, not ready yet") },
+		status:    "failure",
+		condition: "sequence > timeout",
+	},
+}
+
+// stateConditions builds our state conditions from config with the parsers registered for them.  Steps of a sequence
+// are built the same way
+func stateConditions(t *testing.T, config map[interface{}]interface{}) types.StateConditions {
+	conditions := types.StateConditions{Conditions: make(map[string]types.Condition)}
+	for key, value := range config {
+		if key == "sequence" {
+			for _, step := range value.([]interface{}) {
+				conditions.Sequence = append(conditions.Sequence, stateConditions(t, step.(map[interface{}]interface{})))
+			}
+			continue
+		}
+		parse, found := handler.LookupCondition(key.(string))
+		if !found {
+			t.Fatalf("no condition is registered for %v", key)
+		}
+		condition, err := parse(handler.ParseContext{
+			Service:   "db",
+			Path:      "state_conditions." + key.(string),
+			ExportDir: func(dir string) (string, error) { return dir, nil },
+		}, value)
+		if err != nil {
+			t.Fatalf("could not parse %v: %v", key, err)
+		}
+		conditions.Conditions[key.(string)] = condition
+	}
+	return conditions
+}
+
+func TestEvaluate(t *testing.T) {
+	for _, test := range scenarios {
+		t.Run(test.name, func(t *testing.T) {
+			fake := fakedocker.New()
+			fake.Run(fakedocker.Container{Name: "db", Service: "db.local"})
+			if test.setup != nil {
+				test.setup(fake)
+			}
+			container := handler.Container{Client: fake, Name: "db", Events: fake.ServiceEvents("db.local")}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			container_status := make(chan types.ContainerStatus)
+			go handler.Evaluate(ctx, container, stateConditions(t, test.config), container_status)
+
+			// events sent before the handlers are listening are lost, so we wait for them when the scenario relies on
+			// an event.  output is kept by the container, so it can be written at any time
+			if _, found := test.config["exit"]; found {
+				if err := fake.WaitForListener(ctx, "db"); err != nil {
+					t.Fatalf("nobody listened for the events of the container: %v", err)
+				}
+			}
+			if test.run != nil {
+				test.run(fake)
+			}
+
+			select {
+			case status := <-container_status:
+				if status.Status != test.status || status.Condition != test.condition {
+					t.Errorf("expected %v from %v but got %v from %v: %v", test.status, test.condition, status.Status, status.Condition, status.Message)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("expected %v from %v but nothing was reported", test.status, test.condition)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+// Handler provides various state hanlders for our controlled compose run
+package handler
+
+import (
+	"fmt"
+	"github.com/dansteen/controlled-compose/types"
+	"github.com/docker/engine-api/client"
+	dockerTypes "github.com/docker/engine-api/types"
+	"golang.org/x/net/context"
+	"io"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterCondition("exec", parseExec)
+}
+
+// execCondition decides a service based on the exit code of a command run inside its container
+type execCondition struct {
+	check *types.ExecCheck
+}
+
+// parseExec reads the command to run and the exit codes we are looking for.  The command can be provided either as a
+// string to be run by the shell, or as a list of arguments.
+func parseExec(parseContext ParseContext, value interface{}) (Condition, error) {
+	config, err := NewFields(parseContext.Service, parseContext.Path, value)
+	if err != nil {
+		return nil, err
+	}
+	check := &types.ExecCheck{
+		ExitCodes: &types.ExitCodes{Codes: []int{0}},
+		Interval:  1,
+		Status:    "success",
+	}
+	switch command := config.Value("command").(type) {
+	case nil:
+		return nil, config.Fail("command", "missing required key")
+	case string:
+		if strings.TrimSpace(command) == "" {
+			return nil, config.Fail("command", "expected a command but found an empty string")
+		}
+		check.Command = []string{"/bin/sh", "-c", command}
+	case []interface{}:
+		for index, argument := range command {
+			value, ok := argument.(string)
+			if !ok {
+				return nil, config.Fail(fmt.Sprintf("command[%v]", index), "expected a string but found %v", Describe(argument))
+			}
+			check.Command = append(check.Command, value)
+		}
+	default:
+		return nil, config.Fail("command", "expected a command string or a list of arguments but found %v", Describe(command))
+	}
+	for _, err := range []error{
+		config.Ints("exit", &check.ExitCodes.Codes),
+		config.Float("interval", &check.Interval),
+		config.Int("retries", &check.Retries),
+		config.Status("status", &check.Status),
+		config.Unknown(),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(check.Command) == 0 || check.Command[0] == "" {
+		return nil, config.Fail("command", "expected at least one argument")
+	}
+	if check.Interval <= 0 {
+		return nil, config.Fail("interval", "expected an interval greater than 0")
+	}
+	if check.Retries < 0 {
+		return nil, config.Fail("retries", "expected 0 or more retries")
+	}
+	return &execCondition{check: check}, nil
+}
+
+// Describe implements types.Condition.Describe
+func (c *execCondition) Describe() string {
+	check := c.check
+	description := fmt.Sprintf("%v on %v exiting with %v every %vs", check.Status, check.Command, check.ExitCodes.Codes, check.Interval)
+	if check.Retries > 0 {
+		description = fmt.Sprintf("%v for %v attempts", description, check.Retries)
+	}
+	return description
+}
+
+// Run implements Condition.Run
+func (c *execCondition) Run(ctx context.Context, container Container, container_status chan<- types.ContainerStatus) {
+	Exec(ctx, container.out(), container.Client, container.Name, c.check, container_status)
+}
+
+// Exec handles state conditions that result from running a command inside a container
+func Exec(ctx context.Context, out io.Writer, client client.APIClient, container_name string, check *types.ExecCheck, container_status chan<- types.ContainerStatus) {
+	interval := time.Duration(check.Interval * float64(time.Second))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for attempt := 1; ; attempt++ {
+		exit_code, err := runExec(ctx, client, container_name, check.Command)
+		// if we were told we are done while the command was running we just go away
+		if ctx.Err() != nil || (err == nil && exit_code == nil) {
+			fmt.Fprintf(out, "Exiting exec handler for %v\n", container_name)
+			return
+		}
+		var outcome string
+		if err != nil {
+			// the container may have only just exited, or docker may be busy.  either way the command did not tell us
+			// the service is ready, so we count it as a failed attempt and leave it to our retries (or another
+			// condition) to decide the service
+			outcome = fmt.Sprintf("Last attempt could not be run: %v", err)
+			fmt.Fprintf(out, "exec attempt %v in %v failed: %v\n", attempt, container_name, err)
+		} else if check.ExitCodes.Contains(*exit_code) {
+			report(ctx, container_status, types.ContainerStatus{
+				Status:    check.Status,
+				Condition: "exec",
+				Message:   fmt.Sprintf("%v exited with exit code %v.  %v.\n", check.Command, *exit_code, check.Status),
+			})
+			// once we have found a match we don't continue
+			return
+		} else {
+			outcome = fmt.Sprintf("Last exit code was %v", *exit_code)
+		}
+		// if we have run out of retries we give up
+		if check.Retries > 0 && attempt >= check.Retries {
+			report(ctx, container_status, types.ContainerStatus{
+				Status:    "failure",
+				Condition: "exec",
+				Message:   fmt.Sprintf("%v did not exit with one of %v after %v attempts. %v", check.Command, check.ExitCodes.Codes, attempt, outcome),
+			})
+			return
+		}
+		// wait for our next attempt, or exit if we get signalled that we are done
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(out, "Exiting exec handler for %v\n", container_name)
+			return
+		case <-ticker.C:
+			// try again
+		}
+	}
+}
+
+// runExec runs command inside of a container and waits for it to exit.  It returns the exit code of the command, or
+// nil if ctx was cancelled before the command exited
+func runExec(ctx context.Context, client client.APIClient, container_name string, command []string) (*int, error) {
+	exec, err := client.ContainerExecCreate(ctx, container_name, dockerTypes.ExecConfig{
+		Cmd:    command,
+		Detach: true,
+	})
+	if err != nil {
+		return nil, &types.DockerAPIError{Op: fmt.Sprintf("create exec in %v", container_name), Err: err}
+	}
+	err = client.ContainerExecStart(ctx, exec.ID, dockerTypes.ExecStartCheck{Detach: true})
+	if err != nil {
+		return nil, &types.DockerAPIError{Op: fmt.Sprintf("start exec in %v", container_name), Err: err}
+	}
+
+	// the command runs in the background so we poll until it has finished
+	for {
+		info, err := client.ContainerExecInspect(ctx, exec.ID)
+		if err != nil {
+			return nil, &types.DockerAPIError{Op: fmt.Sprintf("inspect exec in %v", container_name), Err: err}
+		}
+		if !info.Running {
+			return &info.ExitCode, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-time.After(100 * time.Millisecond):
+			// check again
+		}
+	}
+}
@@ -7,52 +7,101 @@ import (
 	"github.com/docker/engine-api/client"
 	"github.com/docker/libcompose/project/events"
 	"golang.org/x/net/context"
-	"log"
+	"io"
 )
 
+func init() {
+	RegisterCondition("exit", parseExit)
+}
+
+// exitCondition decides a service based on the exit code of its container
+type exitCondition struct {
+	exitCodes *types.ExitCodes
+}
+
+// parseExit reads the list of exit codes that count as success
+func parseExit(parseContext ParseContext, value interface{}) (Condition, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, parseContext.Fail("expected a list but found %v", Describe(value))
+	}
+	codes := make([]int, 0, len(list))
+	for index, item := range list {
+		code, ok := wholeNumber(item)
+		if !ok {
+			return nil, &FieldError{Service: parseContext.Service, Field: fmt.Sprintf("%v[%v]", parseContext.Path, index), Message: fmt.Sprintf("expected a whole number but found %v", Describe(item))}
+		}
+		codes = append(codes, code)
+	}
+	return &exitCondition{exitCodes: &types.ExitCodes{Codes: codes}}, nil
+}
+
+// Describe implements types.Condition.Describe
+func (c *exitCondition) Describe() string {
+	return fmt.Sprintf("%v", c.exitCodes.Codes)
+}
+
+// Run implements Condition.Run
+func (c *exitCondition) Run(ctx context.Context, container Container, container_status chan<- types.ContainerStatus) {
+	exit_events, err := container.Events(ctx)
+	if err != nil {
+		reportError(ctx, container_status, "exit", &types.DockerAPIError{Op: fmt.Sprintf("listen for events from %v", container.Name), Err: err})
+		return
+	}
+	Exit(ctx, container.out(), container.Client, exit_events, container_status, c.exitCodes)
+}
+
 // Exit will handle the case where a container exits for whatever reason.
-func Exit(client client.APIClient, container_events <-chan events.ContainerEvent, container_status chan<- types.ContainerStatus, exit_codes *types.ExitCodes, done <-chan struct{}) {
-	for event := range container_events {
+func Exit(ctx context.Context, out io.Writer, client client.APIClient, container_events <-chan events.ContainerEvent, container_status chan<- types.ContainerStatus, exit_codes *types.ExitCodes) {
+	for {
 		select {
-		case <-done:
-			fmt.Println("Exiting Exit handler")
+		case <-ctx.Done():
+			fmt.Fprintln(out, "Exiting Exit handler")
 			return
-		default:
-			fmt.Printf("%+v\n", event)
+		case event, ok := <-container_events:
+			// our event stream goes away once our context has been cancelled
+			if !ok {
+				return
+			}
+			fmt.Fprintf(out, "%+v\n", event)
 			// if the container has died
 			if event.Event == "die" {
 				// grab some information about the container that died
-				info, err := client.ContainerInspect(context.Background(), event.ID)
-				container_exit_code := info.ContainerJSONBase.State.ExitCode
+				info, err := client.ContainerInspect(ctx, event.ID)
 				if err != nil {
-					log.Fatal(err)
+					reportError(ctx, container_status, "exit", &types.DockerAPIError{Op: fmt.Sprintf("inspect container %v", event.ID), Err: err})
+					return
 				}
+				container_exit_code := info.ContainerJSONBase.State.ExitCode
 
 				// check our conditions
 				var status types.ContainerStatus
 				// first check if this should not have died at all
 				if exit_codes.Contains(-1) {
 					status = types.ContainerStatus{
-						Status:  "failure",
-						Message: "Container exited but was expected to persist.",
+						Status:    "failure",
+						Condition: "exit",
+						Message:   "Container exited but was expected to persist.",
 					}
 					// then check if our exit code is not listed in the successes
 				} else if !exit_codes.Contains(container_exit_code) {
 					status = types.ContainerStatus{
-						Status:  "failure",
-						Message: fmt.Sprintf("Container exited error code %v", container_exit_code),
+						Status:    "failure",
+						Condition: "exit",
+						Message:   fmt.Sprintf("Container exited error code %v", container_exit_code),
 					}
 					// else it is a success yay!
 				} else {
 					// if it matches what we expected, we exit with success
 					status = types.ContainerStatus{
-						Status:  "success",
-						Message: fmt.Sprintf("Container exited succesfully with exit code %v", container_exit_code),
+						Status:    "success",
+						Condition: "exit",
+						Message:   fmt.Sprintf("Container exited succesfully with exit code %v", container_exit_code),
 					}
 
 				}
 				// report back our exit
-				container_status <- status
+				report(ctx, container_status, status)
 			}
 		}
 	}
@@ -5,35 +5,161 @@ import (
 	"fmt"
 	"github.com/dansteen/controlled-compose/types"
 	"github.com/hpcloud/tail"
-	"log"
+	"golang.org/x/net/context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 )
 
-// FileMonitor handles state conditions that result from content written to files
-func FileMonitor(filename string, monitors []types.FileMonitor, container_status chan<- types.ContainerStatus, done <-chan struct{}) {
+func init() {
+	RegisterCondition("filemonitor", parseFileMonitor)
+}
+
+// fileMonitorCondition decides a service based on what it writes to STDOUT, STDERR or files inside its container
+type fileMonitorCondition struct {
+	// our monitors, grouped by the file they watch
+	monitors map[string][]types.FileMonitor
+}
+
+// parseFileMonitor reads our list of monitors.  Files other than STDOUT and STDERR have their folder exported so we
+// can watch them from the host.
+func parseFileMonitor(parseContext ParseContext, value interface{}) (Condition, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, parseContext.Fail("expected a list but found %v", Describe(value))
+	}
+	condition := &fileMonitorCondition{monitors: make(map[string][]types.FileMonitor)}
+	for index, monitorRaw := range list {
+		monitorConfig, err := NewFields(parseContext.Service, fmt.Sprintf("%v[%v]", parseContext.Path, index), monitorRaw)
+		if err != nil {
+			return nil, err
+		}
+		monitor := types.FileMonitor{}
+		for _, err := range []error{
+			monitorConfig.Require("file", "regex", "status"),
+			monitorConfig.String("file", &monitor.File),
+			monitorConfig.Regex("regex", &monitor.Regex),
+			monitorConfig.Status("status", &monitor.Status),
+			monitorConfig.Unknown(),
+		} {
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// we need to make sure that any folders that are being monitored are exported, and we watch them from where
+		// they end up on the host
+		if monitor.File != "STDOUT" && monitor.File != "STDERR" {
+			hostDir, err := parseContext.ExportDir(filepath.Dir(monitor.File))
+			if err != nil {
+				return nil, err
+			}
+			monitor.HostFile = filepath.Join(hostDir, filepath.Base(monitor.File))
+		}
+		condition.monitors[monitor.File] = append(condition.monitors[monitor.File], monitor)
+	}
+	return condition, nil
+}
+
+// files returns the files we watch in a stable order
+func (c *fileMonitorCondition) files() []string {
+	files := make([]string, 0, len(c.monitors))
+	for filename := range c.monitors {
+		files = append(files, filename)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Describe implements types.Condition.Describe
+func (c *fileMonitorCondition) Describe() string {
+	descriptions := make([]string, 0)
+	for _, filename := range c.files() {
+		for _, monitor := range c.monitors[filename] {
+			descriptions = append(descriptions, fmt.Sprintf("%v on %v matching /%v/", monitor.Status, filename, monitor.Regex.String()))
+		}
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+// MonitoredFiles implements FileWatcher.MonitoredFiles
+func (c *fileMonitorCondition) MonitoredFiles() []string {
+	files := make([]string, 0)
+	for _, filename := range c.files() {
+		if filename != "STDOUT" && filename != "STDERR" {
+			files = append(files, c.monitors[filename][0].HostFile)
+		}
+	}
+	return files
+}
+
+// Run implements Condition.Run.  Each file is watched separately, and the first match decides the condition.
+func (c *fileMonitorCondition) Run(ctx context.Context, container Container, container_status chan<- types.ContainerStatus) {
+	responses := make(chan types.ContainerStatus)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// depending on what type of file/output we are monitoring we do things a bit differently
+	for filename, monitors := range c.monitors {
+		if filename == "STDOUT" {
+			go Output(ctx, container.out(), container.Client, container.Name, container.Since, true, false, monitors, responses)
+		} else if filename == "STDERR" {
+			go Output(ctx, container.out(), container.Client, container.Name, container.Since, false, true, monitors, responses)
+		} else {
+			go FileMonitor(ctx, container.out(), monitors[0].HostFile, container.Since, monitors, responses)
+		}
+	}
+
+	select {
+	case response := <-responses:
+		report(ctx, container_status, response)
+	case <-ctx.Done():
+	}
+}
+
+// FileMonitor handles state conditions that result from content written to files.  If since is set, only what is
+// written to the file after it is looked at.
+func FileMonitor(ctx context.Context, out io.Writer, filename string, since time.Time, monitors []types.FileMonitor, container_status chan<- types.ContainerStatus) {
+	config := tail.Config{Follow: true, ReOpen: true, MustExist: false, Logger: tail.DiscardingLogger}
+	// files do not record when each line was written, so we start from wherever the file had got to.  a file that
+	// does not exist yet is read from the start once it does
+	if info, err := os.Stat(filename); err == nil && !since.IsZero() {
+		config.Location = &tail.SeekInfo{Offset: info.Size(), Whence: io.SeekStart}
+	}
 	// tail our file
-	tail, err := tail.TailFile(filename, tail.Config{Follow: true, ReOpen: true, MustExist: false, Logger: tail.DiscardingLogger})
+	tail, err := tail.TailFile(filename, config)
 	if err != nil {
-		log.Fatal(err)
+		reportError(ctx, container_status, fmt.Sprintf("filemonitor %v", filename), err)
+		return
 	}
+	// make sure we stop following the file when we are finished with it
+	defer tail.Cleanup()
+	defer tail.Stop()
 
 	// then check for our regexs
-	for line := range tail.Lines {
-		for _, monitor := range monitors {
-			if monitor.Regex.Match([]byte(line.Text)) == true {
-				container_status <- types.ContainerStatus{
-					Status:  monitor.Status,
-					Message: fmt.Sprintf("%v matched %v.  %v.\n", line.Text, monitor.Regex.String(), monitor.Status),
-				}
-				// once we have found a match we don't continue
+	for {
+		select {
+		case <-ctx.Done():
+			// if we get signalled that we are done we exit
+			fmt.Fprintf(out, "Exiting filemonitor for %v\n", filename)
+			return
+		case line, ok := <-tail.Lines:
+			if !ok {
 				return
 			}
-			// if we get signalled that we are done we also exit
-			select {
-			case <-done:
-				fmt.Printf("Exiting filemonitor for %v\n", filename)
-				return
-			default:
-				// nothing
+			for _, monitor := range monitors {
+				if monitor.Regex.Match([]byte(line.Text)) == true {
+					report(ctx, container_status, types.ContainerStatus{
+						Status:    monitor.Status,
+						Condition: fmt.Sprintf("filemonitor %v", monitor.File),
+						Message:   fmt.Sprintf("%v matched %v.  %v.\n", line.Text, monitor.Regex.String(), monitor.Status),
+					})
+					// once we have found a match we don't continue
+					return
+				}
 			}
 		}
 	}
@@ -0,0 +1,107 @@
+// Handler provides various state hanlders for our controlled compose run
+package handler
+
+import (
+	"fmt"
+	"github.com/dansteen/controlled-compose/types"
+	"github.com/docker/engine-api/client"
+	"github.com/docker/libcompose/project/events"
+	"golang.org/x/net/context"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterCondition("healthcheck", parseHealthCheck)
+}
+
+// healthCondition decides a service based on the HEALTHCHECK declared in its image
+type healthCondition struct{}
+
+// parseHealthCheck reads whether we should wait on the HEALTHCHECK.  There is nothing to run if it is turned off.
+func parseHealthCheck(parseContext ParseContext, value interface{}) (Condition, error) {
+	enabled, ok := value.(bool)
+	if !ok {
+		return nil, parseContext.Fail("expected true or false but found %v", Describe(value))
+	}
+	if !enabled {
+		return nil, nil
+	}
+	return &healthCondition{}, nil
+}
+
+// Describe implements types.Condition.Describe
+func (c *healthCondition) Describe() string {
+	return "success on healthy, failure on unhealthy"
+}
+
+// Run implements Condition.Run
+func (c *healthCondition) Run(ctx context.Context, container Container, container_status chan<- types.ContainerStatus) {
+	health_events, err := container.Events(ctx)
+	if err != nil {
+		reportError(ctx, container_status, "healthcheck", &types.DockerAPIError{Op: fmt.Sprintf("listen for events from %v", container.Name), Err: err})
+		return
+	}
+	Health(ctx, container.out(), container.Client, container.Name, health_events, container_status)
+}
+
+// Health handles state conditions that result from the HEALTHCHECK declared in a container image
+func Health(ctx context.Context, out io.Writer, client client.APIClient, container_name string, container_events <-chan events.ContainerEvent, container_status chan<- types.ContainerStatus) {
+	// the container may have reached a health status before we started listening, so we check that first
+	info, err := client.ContainerInspect(ctx, container_name)
+	if err != nil {
+		reportError(ctx, container_status, "healthcheck", &types.DockerAPIError{Op: fmt.Sprintf("inspect container %v", container_name), Err: err})
+		return
+	}
+	if info.ContainerJSONBase.State.Health == nil {
+		report(ctx, container_status, types.ContainerStatus{
+			Status:    "failure",
+			Condition: "healthcheck",
+			Message:   fmt.Sprintf("Container %v does not have a HEALTHCHECK", container_name),
+		})
+		return
+	}
+	if status, found := healthStatus(info.ContainerJSONBase.State.Health.Status); found {
+		report(ctx, container_status, status)
+		return
+	}
+
+	// then wait for it to change
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(out, "Exiting health handler for %v\n", container_name)
+			return
+		case event, ok := <-container_events:
+			if !ok {
+				return
+			}
+			if strings.HasPrefix(event.Event, "health_status:") {
+				if status, found := healthStatus(strings.TrimSpace(strings.TrimPrefix(event.Event, "health_status:"))); found {
+					report(ctx, container_status, status)
+					return
+				}
+			}
+		}
+	}
+}
+
+// healthStatus maps a docker health status to our container status.  It returns false if the health status
+// does not decide the state of the container (i.e. it is still starting)
+func healthStatus(health string) (types.ContainerStatus, bool) {
+	switch health {
+	case "healthy":
+		return types.ContainerStatus{
+			Status:    "success",
+			Condition: "healthcheck",
+			Message:   "Container reported healthy.",
+		}, true
+	case "unhealthy":
+		return types.ContainerStatus{
+			Status:    "failure",
+			Condition: "healthcheck",
+			Message:   "Container reported unhealthy.",
+		}, true
+	}
+	return types.ContainerStatus{}, false
+}
@@ -0,0 +1,176 @@
+// Handler provides various state hanlders for our controlled compose run
+package handler
+
+import (
+	"fmt"
+	"github.com/dansteen/controlled-compose/types"
+	"github.com/docker/engine-api/client"
+	"golang.org/x/net/context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterCondition("http", parseHTTP)
+}
+
+// httpCondition decides a service based on the response of an http endpoint
+type httpCondition struct {
+	check *types.HTTPCheck
+}
+
+// parseHTTP reads the endpoint to poll and the response we are looking for
+func parseHTTP(parseContext ParseContext, value interface{}) (Condition, error) {
+	config, err := NewFields(parseContext.Service, parseContext.Path, value)
+	if err != nil {
+		return nil, err
+	}
+	check := &types.HTTPCheck{
+		Path:     "/",
+		Interval: 1,
+		Timeout:  5,
+		Status:   "success",
+	}
+	for _, err := range []error{
+		config.String("url", &check.URL),
+		config.Int("port", &check.Port),
+		config.String("path", &check.Path),
+		config.Ints("status_codes", &check.StatusCodes),
+		config.Regex("regex", &check.Regex),
+		config.Float("interval", &check.Interval),
+		config.Float("timeout", &check.Timeout),
+		config.Status("status", &check.Status),
+		config.Unknown(),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	// we need to have something to poll
+	if check.URL == "" && check.Port == 0 {
+		return nil, config.Fail("url", "either a url or a port is required")
+	}
+	if check.Interval <= 0 {
+		return nil, config.Fail("interval", "expected an interval greater than 0")
+	}
+	if check.Timeout <= 0 {
+		return nil, config.Fail("timeout", "expected a timeout greater than 0")
+	}
+	return &httpCondition{check: check}, nil
+}
+
+// Describe implements types.Condition.Describe
+func (c *httpCondition) Describe() string {
+	check := c.check
+	target := check.URL
+	if target == "" {
+		target = fmt.Sprintf("container port %v%v", check.Port, urlPath(check.Path))
+	}
+	codes := "any 2xx"
+	if len(check.StatusCodes) > 0 {
+		codes = fmt.Sprintf("%v", check.StatusCodes)
+	}
+	description := fmt.Sprintf("%v on %v returning %v", check.Status, target, codes)
+	if check.Regex != nil {
+		description = fmt.Sprintf("%v matching /%v/", description, check.Regex.String())
+	}
+	return fmt.Sprintf("%v every %vs", description, check.Interval)
+}
+
+// Run implements Condition.Run
+func (c *httpCondition) Run(ctx context.Context, container Container, container_status chan<- types.ContainerStatus) {
+	HTTP(ctx, container.out(), container.Client, container.Name, c.check, container_status)
+}
+
+// HTTP handles state conditions that result from polling an http endpoint
+func HTTP(ctx context.Context, out io.Writer, client client.APIClient, container_name string, check *types.HTTPCheck, container_status chan<- types.ContainerStatus) {
+	interval := time.Duration(check.Interval * float64(time.Second))
+
+	// if we were not given a full url we build one from the address of the container
+	url := check.URL
+	if url == "" {
+		ip, err := containerIP(ctx, client, container_name)
+		if err != nil {
+			reportError(ctx, container_status, "http", err)
+			return
+		}
+		url = fmt.Sprintf("http://%v:%v%v", ip, check.Port, urlPath(check.Path))
+	}
+
+	// we don't want a hung request to hold up our polling, but a slow one still gets its chance to answer
+	httpClient := &http.Client{Timeout: time.Duration(check.Timeout * float64(time.Second))}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if matched, message := pollHTTP(ctx, httpClient, url, check); matched {
+			report(ctx, container_status, types.ContainerStatus{
+				Status:    check.Status,
+				Condition: "http",
+				Message:   fmt.Sprintf("%v %v.  %v.\n", url, message, check.Status),
+			})
+			// once we have found a match we don't continue
+			return
+		}
+		// wait for our next poll, or exit if we get signalled that we are done
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(out, "Exiting http handler for %v\n", container_name)
+			return
+		case <-ticker.C:
+			// poll again
+		}
+	}
+}
+
+// urlPath makes sure path can be put straight after the port of a url
+func urlPath(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		return "/" + path
+	}
+	return path
+}
+
+// pollHTTP makes a single request to url and reports whether the response matched check.  The request is abandoned
+// if ctx is cancelled.
+func pollHTTP(ctx context.Context, httpClient *http.Client, url string, check *types.HTTPCheck) (bool, string) {
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return false, err.Error()
+	}
+	request.Cancel = ctx.Done()
+	response, err := httpClient.Do(request)
+	// errors here are expected while the application is starting up so we just try again later
+	if err != nil {
+		return false, err.Error()
+	}
+	defer response.Body.Close()
+
+	// check our status code
+	if len(check.StatusCodes) == 0 {
+		if response.StatusCode < 200 || response.StatusCode > 299 {
+			return false, fmt.Sprintf("returned status code %v", response.StatusCode)
+		}
+	} else {
+		codes := types.ExitCodes{Codes: check.StatusCodes}
+		if !codes.Contains(response.StatusCode) {
+			return false, fmt.Sprintf("returned status code %v", response.StatusCode)
+		}
+	}
+
+	// then check the body if we need to
+	if check.Regex != nil {
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			return false, err.Error()
+		}
+		if !check.Regex.Match(body) {
+			return false, fmt.Sprintf("returned a body that did not match %v", check.Regex.String())
+		}
+		return true, fmt.Sprintf("returned status code %v and matched %v", response.StatusCode, check.Regex.String())
+	}
+	return true, fmt.Sprintf("returned status code %v", response.StatusCode)
+}
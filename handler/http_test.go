@@ -0,0 +1,74 @@
+package handler_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dansteen/controlled-compose/fakedocker"
+	"github.com/dansteen/controlled-compose/handler"
+	"github.com/dansteen/controlled-compose/types"
+	"golang.org/x/net/context"
+)
+
+var httpTests = []struct {
+	name string
+	// how long the endpoint takes to answer
+	delay time.Duration
+	// the interval and timeout of the check, in seconds
+	interval float64
+	timeout  float64
+	// whether we expect the endpoint to be found before we give up
+	matched bool
+}{
+	{
+		name:     "an endpoint slower than the interval is waited for",
+		delay:    200 * time.Millisecond,
+		interval: 0.05,
+		timeout:  5,
+		matched:  true,
+	},
+	{
+		name:     "an endpoint slower than the timeout is given up on",
+		delay:    time.Second,
+		interval: 0.05,
+		timeout:  0.1,
+		matched:  false,
+	},
+}
+
+func TestHTTP(t *testing.T) {
+	for _, test := range httpTests {
+		t.Run(test.name, func(t *testing.T) {
+			delay := test.delay
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(delay):
+					fmt.Fprintln(w, "ok")
+				case <-r.Context().Done():
+				}
+			}))
+			defer server.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 600*time.Millisecond)
+			defer cancel()
+			check := &types.HTTPCheck{URL: server.URL, Interval: test.interval, Timeout: test.timeout, Status: "success"}
+			container_status := make(chan types.ContainerStatus, 1)
+			handler.HTTP(ctx, ioutil.Discard, fakedocker.New(), "web", check, container_status)
+
+			select {
+			case status := <-container_status:
+				if !test.matched {
+					t.Errorf("expected the endpoint to be given up on but got %+v", status)
+				}
+			default:
+				if test.matched {
+					t.Errorf("expected the endpoint to be found before %v", ctx.Err())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// Handler provides various state hanlders for our controlled compose run
+package handler
+
+import (
+	"fmt"
+	"github.com/dansteen/controlled-compose/types"
+	"github.com/docker/engine-api/client"
+	"github.com/docker/go-connections/nat"
+	"golang.org/x/net/context"
+)
+
+// containerIP will find the ip address of a container on the first network it is attached to
+func containerIP(ctx context.Context, client client.APIClient, container_name string) (string, error) {
+	info, err := client.ContainerInspect(ctx, container_name)
+	if err != nil {
+		return "", &types.DockerAPIError{Op: fmt.Sprintf("inspect container %v", container_name), Err: err}
+	}
+	for _, network := range info.NetworkSettings.Networks {
+		if network.IPAddress != "" {
+			return network.IPAddress, nil
+		}
+	}
+	if info.NetworkSettings.IPAddress != "" {
+		return info.NetworkSettings.IPAddress, nil
+	}
+	return "", fmt.Errorf("Could not find an ip address for container %v", container_name)
+}
+
+// containerAddress will find the host:port we can use to reach port on a container.  If published is true we
+// look up the port that docker published on the host for it, otherwise we use the ip address of the container.
+func containerAddress(ctx context.Context, client client.APIClient, container_name string, port int, published bool) (string, error) {
+	if !published {
+		ip, err := containerIP(ctx, client, container_name)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%v:%v", ip, port), nil
+	}
+
+	info, err := client.ContainerInspect(ctx, container_name)
+	if err != nil {
+		return "", &types.DockerAPIError{Op: fmt.Sprintf("inspect container %v", container_name), Err: err}
+	}
+	bindings := info.NetworkSettings.Ports[nat.Port(fmt.Sprintf("%v/tcp", port))]
+	if len(bindings) == 0 {
+		return "", fmt.Errorf("Port %v is not published for container %v", port, container_name)
+	}
+	// a port bound to all interfaces can be reached on the loopback
+	hostIP := bindings[0].HostIP
+	if hostIP == "" || hostIP == "0.0.0.0" {
+		hostIP = "127.0.0.1"
+	}
+	return fmt.Sprintf("%v:%v", hostIP, bindings[0].HostPort), nil
+}
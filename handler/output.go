@@ -8,46 +8,58 @@ import (
 	"github.com/docker/engine-api/client"
 	dockerTypes "github.com/docker/engine-api/types"
 	"golang.org/x/net/context"
-	"log"
+	"io"
+	"time"
 )
 
-// Output will handle state conditions based on STDOUT or STDERR content
-func Output(client client.APIClient, container_name string, stdout bool, stderr bool, monitors []types.FileMonitor, container_status chan<- types.ContainerStatus, done <-chan struct{}) {
+// Output will handle state conditions based on STDOUT or STDERR content.  If since is set, only the output written
+// after it is looked at.
+func Output(ctx context.Context, out io.Writer, client client.APIClient, container_name string, since time.Time, stdout bool, stderr bool, monitors []types.FileMonitor, container_status chan<- types.ContainerStatus) {
 	// if the filename is STDOUT or STDERR we handle it specially
-	logReadCloser, err := client.ContainerLogs(context.Background(), container_name, dockerTypes.ContainerLogsOptions{
+	options := dockerTypes.ContainerLogsOptions{
 		ShowStdout: stdout,
 		ShowStderr: stderr,
 		Follow:     true,
 		Tail:       "all",
-	})
+	}
+	if !since.IsZero() {
+		options.Since = since.Format(time.RFC3339Nano)
+	}
+	logReadCloser, err := client.ContainerLogs(ctx, container_name, options)
 	if err != nil {
-		log.Fatal(err)
+		reportError(ctx, container_status, fmt.Sprintf("filemonitor %v", monitors[0].File), &types.DockerAPIError{Op: fmt.Sprintf("read the logs of %v", container_name), Err: err})
+		return
 	}
 
+	// the scanner blocks until there is more output, so we close the stream out from under it once we are done
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(out, "Exiting output handler for %v\n", container_name)
+		case <-finished:
+		}
+		logReadCloser.Close()
+	}()
+
 	scanner := bufio.NewScanner(logReadCloser)
-	defer logReadCloser.Close()
 	// then check for our regexs
 	for scanner.Scan() {
 		for _, monitor := range monitors {
 			if monitor.Regex.Match([]byte(scanner.Text())) == true {
-				container_status <- types.ContainerStatus{
-					Status:  monitor.Status,
-					Message: fmt.Sprintf("%v matched %v.  %v.\n", scanner.Text(), monitor.Regex.String(), monitor.Status),
-				}
+				report(ctx, container_status, types.ContainerStatus{
+					Status:    monitor.Status,
+					Condition: fmt.Sprintf("filemonitor %v", monitor.File),
+					Message:   fmt.Sprintf("%v matched %v.  %v.\n", scanner.Text(), monitor.Regex.String(), monitor.Status),
+				})
 				// once we have found a match we don't continue
 				return
 			}
-			// if we get the message that we are done, we also exit
-			select {
-			case <-done:
-				fmt.Printf("Exiting output handler for %v\n", container_name)
-				return
-			default:
-				// nothing
-			}
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+	// reading from a closed stream is expected once we are done
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		reportError(ctx, container_status, fmt.Sprintf("filemonitor %v", monitors[0].File), &types.DockerAPIError{Op: fmt.Sprintf("read the logs of %v", container_name), Err: err})
 	}
 }
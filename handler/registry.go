@@ -0,0 +1,84 @@
+// Handler provides various state hanlders for our controlled compose run
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dansteen/controlled-compose/types"
+	"golang.org/x/net/context"
+)
+
+// Condition is a single kind of state condition.  Each one is parsed from its own key under state_conditions, and
+// run against the container of a service until it has decided whether the service succeeded or failed.
+type Condition interface {
+	types.Condition
+	// Run watches container and reports the status that decides this condition on container_status.  It must
+	// return once ctx has been cancelled, and should send its status with report so it does not block once nobody
+	// is listening.
+	Run(ctx context.Context, container Container, container_status chan<- types.ContainerStatus)
+}
+
+// FileWatcher is implemented by conditions that watch files in the container, so their contents can be included
+// in failure reports.  The files are given as they are found on the host
+type FileWatcher interface {
+	MonitoredFiles() []string
+}
+
+// ParseContext holds what a condition parser needs to know about where its config was found
+type ParseContext struct {
+	// Service is the name of the service the condition belongs to
+	Service string
+	// Path is the key path to the config of the condition, such as state_conditions.all_of[0].exit
+	Path string
+	// ExportDir makes sure dir, a directory inside the container, is exported as a volume so it can be read from
+	// the host, and returns the directory on the host it can be read from
+	ExportDir func(dir string) (string, error)
+}
+
+// Fail builds an error for the config of the condition
+func (c ParseContext) Fail(format string, args ...interface{}) error {
+	return &FieldError{Service: c.Service, Field: c.Path, Message: fmt.Sprintf(format, args...)}
+}
+
+// ConditionParser builds a condition from the value found under its key in a state_conditions stanza.  A parser can
+// return a nil Condition if the value turns the condition off (such as healthcheck: false).
+type ConditionParser func(parseContext ParseContext, value interface{}) (Condition, error)
+
+// our registered conditions, keyed by their state_conditions key
+var (
+	conditions     = make(map[string]ConditionParser)
+	conditionsLock sync.RWMutex
+)
+
+// RegisterCondition makes a condition available under key in state_conditions.  Conditions normally register
+// themselves from an init function in the file that implements them.
+func RegisterCondition(key string, parse ConditionParser) {
+	conditionsLock.Lock()
+	defer conditionsLock.Unlock()
+	if _, found := conditions[key]; found {
+		panic(fmt.Sprintf("a condition is already registered for %v", key))
+	}
+	conditions[key] = parse
+}
+
+// LookupCondition returns the parser registered for key
+func LookupCondition(key string) (ConditionParser, bool) {
+	conditionsLock.RLock()
+	defer conditionsLock.RUnlock()
+	parse, found := conditions[key]
+	return parse, found
+}
+
+// RegisteredConditions returns the keys of our registered conditions in a stable order
+func RegisteredConditions() []string {
+	conditionsLock.RLock()
+	defer conditionsLock.RUnlock()
+	keys := make([]string, 0, len(conditions))
+	for key := range conditions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+// Handler provides various state hanlders for our controlled compose run
+package handler
+
+import (
+	"fmt"
+	"github.com/dansteen/controlled-compose/types"
+	"github.com/docker/engine-api/client"
+	"golang.org/x/net/context"
+	"io"
+	"net"
+	"time"
+)
+
+func init() {
+	RegisterCondition("tcp", parseTCP)
+}
+
+// tcpCondition decides a service once a port accepts connections
+type tcpCondition struct {
+	check *types.TCPCheck
+}
+
+// parseTCP reads the port to connect to and how often to try
+func parseTCP(parseContext ParseContext, value interface{}) (Condition, error) {
+	config, err := NewFields(parseContext.Service, parseContext.Path, value)
+	if err != nil {
+		return nil, err
+	}
+	check := &types.TCPCheck{
+		Interval: 1,
+		Status:   "success",
+	}
+	for _, err := range []error{
+		config.Require("port"),
+		config.Int("port", &check.Port),
+		config.Bool("published", &check.Published),
+		config.Float("interval", &check.Interval),
+		config.Status("status", &check.Status),
+		config.Unknown(),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	if check.Interval <= 0 {
+		return nil, config.Fail("interval", "expected an interval greater than 0")
+	}
+	return &tcpCondition{check: check}, nil
+}
+
+// Describe implements types.Condition.Describe
+func (c *tcpCondition) Describe() string {
+	published := ""
+	if c.check.Published {
+		published = " (published)"
+	}
+	return fmt.Sprintf("%v on port %v%v accepting connections every %vs", c.check.Status, c.check.Port, published, c.check.Interval)
+}
+
+// Run implements Condition.Run
+func (c *tcpCondition) Run(ctx context.Context, container Container, container_status chan<- types.ContainerStatus) {
+	TCP(ctx, container.out(), container.Client, container.Name, c.check, container_status)
+}
+
+// TCP handles state conditions that result from a port accepting connections
+func TCP(ctx context.Context, out io.Writer, client client.APIClient, container_name string, check *types.TCPCheck, container_status chan<- types.ContainerStatus) {
+	interval := time.Duration(check.Interval * float64(time.Second))
+
+	// find out where we should be connecting to
+	address, err := containerAddress(ctx, client, container_name, check.Port, check.Published)
+	if err != nil {
+		reportError(ctx, container_status, "tcp", err)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// connection errors are expected while the application is starting up so we just try again later
+		conn, err := net.DialTimeout("tcp", address, interval)
+		if err == nil {
+			conn.Close()
+			report(ctx, container_status, types.ContainerStatus{
+				Status:    check.Status,
+				Condition: "tcp",
+				Message:   fmt.Sprintf("%v accepted a connection.  %v.\n", address, check.Status),
+			})
+			// once we have connected we don't continue
+			return
+		}
+		// wait for our next attempt, or exit if we get signalled that we are done
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(out, "Exiting tcp handler for %v\n", container_name)
+			return
+		case <-ticker.C:
+			// try again
+		}
+	}
+}
@@ -4,24 +4,67 @@ package handler
 import (
 	"fmt"
 	"github.com/dansteen/controlled-compose/types"
+	"golang.org/x/net/context"
+	"io"
 	"time"
 )
 
+func init() {
+	RegisterCondition("timeout", parseTimeout)
+}
+
+// timeoutCondition decides a service once it has been given a set amount of time
+type timeoutCondition struct {
+	timeout *types.Timeout
+}
+
+// parseTimeout reads how long to wait and the status to report once we have waited that long
+func parseTimeout(parseContext ParseContext, value interface{}) (Condition, error) {
+	config, err := NewFields(parseContext.Service, parseContext.Path, value)
+	if err != nil {
+		return nil, err
+	}
+	timeout := &types.Timeout{}
+	for _, err := range []error{
+		config.Require("duration", "status"),
+		config.Duration("duration", &timeout.Duration),
+		config.Status("status", &timeout.Status),
+		config.Unknown(),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &timeoutCondition{timeout: timeout}, nil
+}
+
+// Describe implements types.Condition.Describe
+func (c *timeoutCondition) Describe() string {
+	return fmt.Sprintf("%v after %v", c.timeout.Status, c.timeout.Duration)
+}
+
+// Run implements Condition.Run
+func (c *timeoutCondition) Run(ctx context.Context, container Container, container_status chan<- types.ContainerStatus) {
+	Timeout(ctx, container.out(), c.timeout, container_status)
+}
+
 // Timeout will handle timeout state conditions
-func Timeout(timeout *types.Timeout, timeout_triggered chan<- types.ContainerStatus, done <-chan struct{}) {
+func Timeout(ctx context.Context, out io.Writer, timeout *types.Timeout, timeout_triggered chan<- types.ContainerStatus) {
 	// start a timer
-	timer := time.NewTimer(time.Second * time.Duration(timeout.Duration))
+	timer := time.NewTimer(timeout.Duration)
+	defer timer.Stop()
 	// wait for the timer to run out or for us to be signaled we no longer need to wait
 	select {
 	case <-timer.C:
 		// respond
-		timeout_triggered <- types.ContainerStatus{
-			Status:  timeout.Status,
-			Message: fmt.Sprintf("%v triggered after %v seconds", timeout.Status, timeout.Duration),
-		}
+		report(ctx, timeout_triggered, types.ContainerStatus{
+			Status:    timeout.Status,
+			Condition: "timeout",
+			Message:   fmt.Sprintf("%v triggered after %v", timeout.Status, timeout.Duration),
+		})
 		return
-	case <-done:
-		fmt.Println("Exiting timeout handler")
+	case <-ctx.Done():
+		fmt.Fprintln(out, "Exiting timeout handler")
 		return
 	}
 
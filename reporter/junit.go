@@ -0,0 +1,152 @@
+// Reporter provides structured reporting of the progress of our controlled compose run
+package reporter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+)
+
+// junitTestSuite is the root element of a junit report
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase holds the result of starting a single service
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitFailure holds the reason a service failed
+type junitFailure struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// junitSkipped marks a service that was never started
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// JUnitReporter collects the results of each service so they can be written out as a junit report once our run
+// is finished
+type JUnitReporter struct {
+	name     string
+	planned  []string
+	order    []string
+	started  map[string]time.Time
+	finished map[string]Event
+	runStart time.Time
+	runEnd   time.Time
+	lock     sync.Mutex
+}
+
+// NewJUnit creates a junit reporter for the project name provided
+func NewJUnit(name string) *JUnitReporter {
+	return &JUnitReporter{
+		name:     name,
+		started:  make(map[string]time.Time),
+		finished: make(map[string]Event),
+	}
+}
+
+// Report implements Reporter.Report
+func (r *JUnitReporter) Report(event Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	switch event.Type {
+	case RunStarting:
+		r.runStart = event.Time
+		r.planned = event.Services
+	case ServiceStarting:
+		r.order = append(r.order, event.Service)
+		r.started[event.Service] = event.Time
+	case ServiceSucceeded, ServiceFailed:
+		r.finished[event.Service] = event
+	case RunFinished:
+		r.runEnd = event.Time
+	}
+}
+
+// Write saves our report to path
+func (r *JUnitReporter) Write(path string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	suite := junitTestSuite{
+		Name: r.name,
+		Time: seconds(r.runEnd.Sub(r.runStart)),
+	}
+	// services that were started come first, in the order they were started in
+	for _, service := range r.order {
+		testCase := junitTestCase{
+			Name:      service,
+			ClassName: r.name,
+		}
+		event, found := r.finished[service]
+		if !found {
+			// this can only happen if we were interrupted while the service was starting
+			testCase.Failure = &junitFailure{Message: "Service did not finish starting", Type: "interrupted"}
+		} else {
+			testCase.Time = seconds(event.Time.Sub(r.started[service]))
+			condition := ""
+			if event.Status != nil {
+				condition = event.Status.Condition
+				testCase.SystemOut = fmt.Sprintf("Decided by %v: %v", condition, strings.TrimSpace(event.Status.Message))
+			}
+			if event.Type == ServiceFailed {
+				if condition == "" {
+					condition = "error"
+				}
+				testCase.Failure = &junitFailure{
+					Message:  event.Message,
+					Type:     condition,
+					Contents: event.Details,
+				}
+			}
+		}
+		if testCase.Failure != nil {
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	// then anything we never got to
+	for _, service := range r.planned {
+		if _, found := r.started[service]; found {
+			continue
+		}
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      service,
+			ClassName: r.name,
+			Skipped:   &junitSkipped{Message: "Service was not started"},
+		})
+		suite.Skipped++
+	}
+	suite.Tests = len(suite.TestCases)
+
+	content, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append([]byte(xml.Header), content...), 0644)
+}
+
+// seconds formats a duration the way junit expects it
+func seconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
@@ -0,0 +1,118 @@
+package reporter_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dansteen/controlled-compose/reporter"
+	"github.com/dansteen/controlled-compose/types"
+)
+
+// at returns the time the given number of seconds into a run
+func at(seconds float64) time.Time {
+	return time.Date(2016, 7, 1, 12, 0, 0, 0, time.UTC).Add(time.Duration(seconds * float64(time.Second)))
+}
+
+var junitTests = []struct {
+	name   string
+	events []reporter.Event
+	xml    string
+}{
+	{
+		name: "every service succeeds",
+		events: []reporter.Event{
+			{Time: at(0), Type: reporter.RunStarting, Services: []string{"db", "web"}},
+			{Time: at(0), Type: reporter.ServiceStarting, Service: "db"},
+			{Time: at(2.5), Type: reporter.ServiceSucceeded, Service: "db", Status: &types.ContainerStatus{Status: "success", Condition: "exit", Message: "db exited with 0.  success.\n"}},
+			{Time: at(2.5), Type: reporter.ServiceStarting, Service: "web"},
+			{Time: at(3), Type: reporter.ServiceSucceeded, Service: "web"},
+			{Time: at(3), Type: reporter.RunFinished},
+		},
+		xml: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="myproject" tests="2" failures="0" skipped="0" time="3.000">
+  <testcase name="db" classname="myproject" time="2.500">
+    <system-out>Decided by exit: db exited with 0.  success.</system-out>
+  </testcase>
+  <testcase name="web" classname="myproject" time="0.500"></testcase>
+</testsuite>`,
+	},
+	{
+		name: "a failure skips the services after it",
+		events: []reporter.Event{
+			{Time: at(0), Type: reporter.RunStarting, Services: []string{"db", "web"}},
+			{Time: at(0), Type: reporter.ServiceStarting, Service: "db"},
+			{
+				Time:    at(1),
+				Type:    reporter.ServiceFailed,
+				Service: "db",
+				Status:  &types.ContainerStatus{Status: "failure", Condition: "filemonitor STDOUT", Message: "FATAL matched FATAL"},
+				Message: "Container db exited with an error: FATAL matched FATAL",
+				Details: "==== Failure report for service db <1> & more ====",
+			},
+			{Time: at(1), Type: reporter.RunFinished},
+		},
+		xml: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="myproject" tests="2" failures="1" skipped="1" time="1.000">
+  <testcase name="db" classname="myproject" time="1.000">
+    <failure message="Container db exited with an error: FATAL matched FATAL" type="filemonitor STDOUT">==== Failure report for service db &lt;1&gt; &amp; more ====</failure>
+    <system-out>Decided by filemonitor STDOUT: FATAL matched FATAL</system-out>
+  </testcase>
+  <testcase name="web" classname="myproject" time="">
+    <skipped message="Service was not started"></skipped>
+  </testcase>
+</testsuite>`,
+	},
+	{
+		name: "a service that could not be decided fails as an error",
+		events: []reporter.Event{
+			{Time: at(0), Type: reporter.RunStarting, Services: []string{"db"}},
+			{Time: at(0), Type: reporter.ServiceStarting, Service: "db"},
+			{Time: at(0.25), Type: reporter.ServiceFailed, Service: "db", Message: "Could not create service db: no such image"},
+			{Time: at(0.25), Type: reporter.RunFinished},
+		},
+		xml: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="myproject" tests="1" failures="1" skipped="0" time="0.250">
+  <testcase name="db" classname="myproject" time="0.250">
+    <failure message="Could not create service db: no such image" type="error"></failure>
+  </testcase>
+</testsuite>`,
+	},
+	{
+		name: "an interrupted service fails",
+		events: []reporter.Event{
+			{Time: at(0), Type: reporter.RunStarting, Services: []string{"db"}},
+			{Time: at(0), Type: reporter.ServiceStarting, Service: "db"},
+			{Time: at(4), Type: reporter.RunFinished},
+		},
+		xml: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="myproject" tests="1" failures="1" skipped="0" time="4.000">
+  <testcase name="db" classname="myproject" time="">
+    <failure message="Service did not finish starting" type="interrupted"></failure>
+  </testcase>
+</testsuite>`,
+	},
+}
+
+func TestJUnit(t *testing.T) {
+	for _, test := range junitTests {
+		t.Run(test.name, func(t *testing.T) {
+			junit := reporter.NewJUnit("myproject")
+			for _, event := range test.events {
+				junit.Report(event)
+			}
+			path := filepath.Join(t.TempDir(), "junit.xml")
+			if err := junit.Write(path); err != nil {
+				t.Fatal(err)
+			}
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != test.xml {
+				t.Errorf("expected:\n%v\nbut got:\n%v", test.xml, string(content))
+			}
+		})
+	}
+}
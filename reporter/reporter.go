@@ -0,0 +1,115 @@
+// Reporter provides structured reporting of the progress of our controlled compose run
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dansteen/controlled-compose/types"
+)
+
+// The types of events we report
+const (
+	RunStarting       = "run_starting"
+	ServiceStarting   = "service_starting"
+	ContainerCreated  = "container_created"
+	ContainerStarted  = "container_started"
+	ConditionMatched  = "condition_matched"
+	ConditionTimedOut = "condition_timed_out"
+	ServiceRetrying   = "service_retrying"
+	ServiceSucceeded  = "service_succeeded"
+	ServiceFailed     = "service_failed"
+	RunFinished       = "run_finished"
+	TimingReport      = "timing_report"
+)
+
+// Event holds a single step in our run
+type Event struct {
+	Time      time.Time              `json:"time"`
+	Type      string                 `json:"type"`
+	Service   string                 `json:"service,omitempty"`
+	Container string                 `json:"container,omitempty"`
+	Status    *types.ContainerStatus `json:"status,omitempty"`
+	Services  []string               `json:"services,omitempty"`
+	Message   string                 `json:"message,omitempty"`
+	Details   string                 `json:"details,omitempty"`
+	Timings   *TimingSummary         `json:"timings,omitempty"`
+}
+
+// Reporter receives the events of a run as they happen
+type Reporter interface {
+	Report(event Event)
+}
+
+// New creates a reporter that writes events to out in the format provided ("text" or "json")
+func New(format string, out io.Writer) (Reporter, error) {
+	switch format {
+	case "text":
+		return &TextReporter{out: out}, nil
+	case "json":
+		return &JSONReporter{encoder: json.NewEncoder(out)}, nil
+	}
+	return nil, fmt.Errorf("Unknown output format %v.  Valid formats are text and json", format)
+}
+
+// Multi sends each event to all of the reporters provided
+type Multi []Reporter
+
+// Report implements Reporter.Report
+func (m Multi) Report(event Event) {
+	for _, r := range m {
+		r.Report(event)
+	}
+}
+
+// TextReporter writes events as human readable lines
+type TextReporter struct {
+	out  io.Writer
+	lock sync.Mutex
+}
+
+// Report implements Reporter.Report
+func (r *TextReporter) Report(event Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	switch event.Type {
+	case RunStarting:
+		fmt.Fprintf(r.out, "Services will be started in the following order: %v\n", event.Services)
+	case ServiceStarting:
+		fmt.Fprintf(r.out, "Starting  up service - %v\n", event.Service)
+	case ContainerCreated:
+		fmt.Fprintf(r.out, "Created container %v for service - %v\n", event.Message, event.Service)
+	case ContainerStarted:
+		fmt.Fprintf(r.out, "Started up service - %v\n", event.Service)
+	case ConditionMatched, ConditionTimedOut:
+		fmt.Fprintf(r.out, "%v - %v: %v\n", event.Service, event.Status.Condition, strings.TrimSpace(event.Status.Message))
+	case ServiceRetrying:
+		fmt.Fprintf(r.out, "Service %v %v\n", event.Service, event.Message)
+	case ServiceSucceeded:
+		fmt.Fprintf(r.out, "Service %v succeeded\n", event.Service)
+	case ServiceFailed:
+		fmt.Fprintf(r.out, "Service %v failed - %v\n", event.Service, event.Message)
+	case RunFinished:
+		fmt.Fprintf(r.out, "%v\n", event.Status.Message)
+	case TimingReport:
+		event.Timings.Write(r.out)
+	}
+}
+
+// JSONReporter writes each event as a single line of json
+type JSONReporter struct {
+	encoder *json.Encoder
+	lock    sync.Mutex
+}
+
+// Report implements Reporter.Report
+func (r *JSONReporter) Report(event Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	// there is not much we can do if we can't write out our events
+	r.encoder.Encode(event)
+}
@@ -0,0 +1,146 @@
+// Reporter provides structured reporting of the progress of our controlled compose run
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// ServiceTiming holds the times at which each step of starting a service happened
+type ServiceTiming struct {
+	Service  string    `json:"service"`
+	Starting time.Time `json:"starting"`
+	Created  time.Time `json:"created"`
+	Started  time.Time `json:"started"`
+	Decided  time.Time `json:"decided"`
+	Status   string    `json:"status"`
+}
+
+// Total returns how long the service took from when we started creating it until its conditions were decided
+func (t ServiceTiming) Total() time.Duration {
+	return t.Decided.Sub(t.Starting)
+}
+
+// TimingSummary holds the timings for each service and the chain of services that determined how long our run took
+type TimingSummary struct {
+	Services     []ServiceTiming `json:"services"`
+	CriticalPath []string        `json:"critical_path"`
+	Total        float64         `json:"total_seconds"`
+}
+
+// TimingReporter records when each step of starting each service happened
+type TimingReporter struct {
+	order   []string
+	timings map[string]*ServiceTiming
+	lock    sync.Mutex
+}
+
+// NewTiming creates a timing reporter
+func NewTiming() *TimingReporter {
+	return &TimingReporter{
+		timings: make(map[string]*ServiceTiming),
+	}
+}
+
+// Report implements Reporter.Report
+func (r *TimingReporter) Report(event Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	switch event.Type {
+	case ServiceStarting:
+		r.order = append(r.order, event.Service)
+		r.timings[event.Service] = &ServiceTiming{Service: event.Service, Starting: event.Time}
+	case ContainerCreated:
+		r.timings[event.Service].Created = event.Time
+	case ContainerStarted:
+		r.timings[event.Service].Started = event.Time
+	case ConditionMatched, ConditionTimedOut:
+		r.timings[event.Service].Decided = event.Time
+	case ServiceSucceeded, ServiceFailed:
+		timing := r.timings[event.Service]
+		// services without conditions are decided as soon as they have started
+		if timing.Decided.IsZero() {
+			timing.Decided = event.Time
+		}
+		timing.Status = "success"
+		if event.Type == ServiceFailed {
+			timing.Status = "failure"
+		}
+	}
+}
+
+// Summary builds our timing summary.  dependencies maps each service to the services it depends on, and is used to
+// work out the critical path: starting from the service that finished last, we repeatedly step back to the
+// dependency that finished last, since that is the one that held up the service after it.
+func (r *TimingReporter) Summary(dependencies map[string][]string) *TimingSummary {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	summary := &TimingSummary{
+		Services:     make([]ServiceTiming, 0),
+		CriticalPath: make([]string, 0),
+	}
+	if len(r.order) == 0 {
+		return summary
+	}
+
+	last := ""
+	for _, service := range r.order {
+		timing := *r.timings[service]
+		summary.Services = append(summary.Services, timing)
+		if last == "" || timing.Decided.After(r.timings[last].Decided) {
+			last = service
+		}
+	}
+
+	// walk back through our dependencies
+	path := []string{last}
+	for current := last; ; {
+		next := ""
+		for _, dep := range dependencies[current] {
+			if timing, found := r.timings[dep]; found {
+				if next == "" || timing.Decided.After(r.timings[next].Decided) {
+					next = dep
+				}
+			}
+		}
+		if next == "" {
+			break
+		}
+		path = append([]string{next}, path...)
+		current = next
+	}
+	summary.CriticalPath = path
+	summary.Total = r.timings[last].Decided.Sub(r.timings[r.order[0]].Starting).Seconds()
+	return summary
+}
+
+// Write writes our summary as a table
+func (s *TimingSummary) Write(out io.Writer) {
+	writer := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "SERVICE\tCREATE\tSTART\tCONDITIONS\tTOTAL\tSTATUS")
+	for _, timing := range s.Services {
+		fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\n",
+			timing.Service,
+			step(timing.Starting, timing.Created),
+			step(timing.Created, timing.Started),
+			step(timing.Started, timing.Decided),
+			seconds(timing.Total()),
+			timing.Status,
+		)
+	}
+	writer.Flush()
+	fmt.Fprintf(out, "Critical path (%vs): %v\n", fmt.Sprintf("%.3f", s.Total), strings.Join(s.CriticalPath, " -> "))
+}
+
+// step formats how long a step took, or a dash if it never finished
+func step(from time.Time, to time.Time) string {
+	if from.IsZero() || to.IsZero() {
+		return "-"
+	}
+	return seconds(to.Sub(from))
+}
@@ -0,0 +1,99 @@
+package reporter_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dansteen/controlled-compose/reporter"
+)
+
+// timedService is a service that started and was decided the given number of seconds into a run
+type timedService struct {
+	name     string
+	starting int
+	decided  int
+	failed   bool
+}
+
+var criticalPathTests = []struct {
+	name         string
+	services     []timedService
+	dependencies map[string][]string
+	path         []string
+	total        float64
+}{
+	{
+		name:     "nothing was started",
+		services: []timedService{},
+		path:     []string{},
+	},
+	{
+		name:         "a chain is its own critical path",
+		services:     []timedService{{"db", 0, 3, false}, {"api", 3, 5, false}, {"web", 5, 6, false}},
+		dependencies: map[string][]string{"api": {"db"}, "web": {"api"}},
+		path:         []string{"db", "api", "web"},
+		total:        6,
+	},
+	{
+		name:         "the dependency that finished last is the one that held us up",
+		services:     []timedService{{"db", 0, 5, false}, {"cache", 0, 8, false}, {"web", 8, 9, false}},
+		dependencies: map[string][]string{"web": {"db", "cache"}},
+		path:         []string{"cache", "web"},
+		total:        9,
+	},
+	{
+		name:         "the service that finished last ends the path even if nothing depends on it",
+		services:     []timedService{{"db", 0, 2, false}, {"worker", 0, 7, false}, {"web", 2, 4, false}},
+		dependencies: map[string][]string{"web": {"db"}},
+		path:         []string{"worker"},
+		total:        7,
+	},
+	{
+		name:         "a failed service still ends the path",
+		services:     []timedService{{"db", 0, 2, false}, {"web", 2, 10, true}},
+		dependencies: map[string][]string{"web": {"db"}},
+		path:         []string{"db", "web"},
+		total:        10,
+	},
+	{
+		name:         "dependencies that were not started are skipped",
+		services:     []timedService{{"api", 0, 3, false}, {"web", 3, 4, false}},
+		dependencies: map[string][]string{"web": {"api", "db"}},
+		path:         []string{"api", "web"},
+		total:        4,
+	},
+}
+
+func TestCriticalPath(t *testing.T) {
+	start := time.Date(2016, 7, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+	for _, test := range criticalPathTests {
+		t.Run(test.name, func(t *testing.T) {
+			timing := reporter.NewTiming()
+			for _, service := range test.services {
+				timing.Report(reporter.Event{Time: at(service.starting), Type: reporter.ServiceStarting, Service: service.name})
+			}
+			for _, service := range test.services {
+				decided := reporter.ServiceSucceeded
+				if service.failed {
+					decided = reporter.ServiceFailed
+				}
+				timing.Report(reporter.Event{Time: at(service.decided), Type: decided, Service: service.name})
+			}
+
+			summary := timing.Summary(test.dependencies)
+			if !reflect.DeepEqual(summary.CriticalPath, test.path) {
+				t.Errorf("expected the critical path %v but got %v", test.path, summary.CriticalPath)
+			}
+			if summary.Total != test.total {
+				t.Errorf("expected a total of %vs but got %vs", test.total, summary.Total)
+			}
+			if len(summary.Services) != len(test.services) {
+				t.Errorf("expected timings for %v services but got %v", len(test.services), len(summary.Services))
+			}
+		})
+	}
+}
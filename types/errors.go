@@ -0,0 +1,86 @@
+package types
+
+import (
+	"fmt"
+)
+
+// DockerAPIError is returned when a call to the docker server fails
+type DockerAPIError struct {
+	// Op describes what we were doing, such as "start service db.local"
+	Op  string
+	Err error
+}
+
+// Error implements the error interface
+func (e *DockerAPIError) Error() string {
+	return fmt.Sprintf("Could not %v: %v", e.Op, e.Err)
+}
+
+// ConfigError is returned when the compose files, or the options we were run with, can not be used
+type ConfigError struct {
+	Err error
+}
+
+// Error implements the error interface
+func (e *ConfigError) Error() string {
+	return e.Err.Error()
+}
+
+// ConditionFailedError is returned when a service fails its state conditions
+type ConditionFailedError struct {
+	Service   string
+	Container string
+	// Status is the status that decided the conditions of the service
+	Status ContainerStatus
+	// Attempts is how many times the service was started, including any retries
+	Attempts int
+	// Report is the failure report for the service, with the state and recent output of its container
+	Report string
+}
+
+// Error implements the error interface
+func (e *ConditionFailedError) Error() string {
+	message := fmt.Sprintf("Container %v exited with an error: %v", e.Container, e.Status.Message)
+	if e.Attempts > 1 {
+		message = fmt.Sprintf("%v (after %v attempts)", message, e.Attempts)
+	}
+	return message
+}
+
+// ConditionError is returned when the state conditions of a service could not be decided because a handler failed,
+// such as when docker could not tell us about the container
+type ConditionError struct {
+	Service   string
+	Container string
+	// Status is the status reported by the handler that failed
+	Status ContainerStatus
+	Err    error
+	// Report is the failure report for the service, with the state and recent output of its container
+	Report string
+}
+
+// Error implements the error interface
+func (e *ConditionError) Error() string {
+	return fmt.Sprintf("Could not decide the state conditions of %v: %v", e.Container, e.Err)
+}
+
+// InterruptedError is returned when we are cancelled before all of our services have started
+type InterruptedError struct {
+	// Err is why we were cancelled, as reported by our context
+	Err error
+}
+
+// Error implements the error interface
+func (e *InterruptedError) Error() string {
+	return "Interrupted before all services had started"
+}
+
+// NoContainerError is returned when a service does not have a container for us to work with
+type NoContainerError struct {
+	Service string
+}
+
+// Error implements the error interface
+func (e *NoContainerError) Error() string {
+	return fmt.Sprintf("Service %v does not have a container", e.Service)
+}
@@ -2,19 +2,29 @@ package types
 
 import (
 	"regexp"
+	"strings"
+	"time"
 )
 
-// ContianerStatus holds the status and related status message of a container
+// ContianerStatus holds the status and related status message of a container.  Status is success or failure, or
+// error if a condition could not be decided because something went wrong, in which case Err holds what it was.
 type ContainerStatus struct {
-	Status  string
-	Message string
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+	Condition string `json:"condition,omitempty"`
+	Err       error  `json:"-"`
 }
 
 // FileMonitor holds information about file monitors for our containers
 type FileMonitor struct {
-	File   string         `the file to monitor`
-	Regex  *regexp.Regexp `the regular expression to look for`
-	Status string         `weather to succeed or fail`
+	// the file to monitor, as it is found inside the container
+	File string
+	// where File can be read from on the host, through the directory it is exported to
+	HostFile string
+	// the regular expression to look for
+	Regex *regexp.Regexp
+	// weather to succeed or fail
+	Status string
 }
 
 // ExitCodes holds a list of exit codes
@@ -39,15 +49,109 @@ func (c *ExitCodes) Len() int {
 
 // Timeout holds information about a timeout that has been specified on a container
 type Timeout struct {
-	Duration float64
+	Duration time.Duration
 	Status   string
 }
 
+// HTTPCheck holds information about an http readiness probe that has been specified on a container
+type HTTPCheck struct {
+	// the full url to poll. if this is not set we build one from the container ip, Port and Path
+	URL string
+	// the port inside the container to poll
+	Port int
+	// the path to request from the container
+	Path string
+	// the http status codes that count as a match. any 2xx code matches if none are provided
+	StatusCodes []int
+	// an optional regular expression the response body must match
+	Regex *regexp.Regexp
+	// how long to wait (in seconds) between polls
+	Interval float64
+	// how long to wait (in seconds) for a response before giving up on a poll
+	Timeout float64
+	// weather to succeed or fail
+	Status string
+}
+
+// TCPCheck holds information about a tcp port readiness probe that has been specified on a container
+type TCPCheck struct {
+	// the port inside the container to connect to
+	Port int
+	// connect to the port docker published on the host rather than the container ip
+	Published bool
+	// how long to wait (in seconds) between connection attempts
+	Interval float64
+	// weather to succeed or fail
+	Status string
+}
+
+// ExecCheck holds information about a command to run inside of a container as a readiness probe
+type ExecCheck struct {
+	// the command to run inside the container
+	Command []string
+	// the exit codes of the command that count as a match
+	ExitCodes *ExitCodes
+	// how long to wait (in seconds) between runs of the command
+	Interval float64
+	// how many times to run the command before failing. 0 means run until another condition triggers
+	Retries int
+	// weather to succeed or fail
+	Status string
+}
+
+// Retry holds how many times to recreate a service that failed its state conditions, and which failures to retry
+type Retry struct {
+	// the total number of times to start the service, including the first
+	Attempts int
+	// how long to wait before the first retry. the wait doubles after each retry
+	Backoff time.Duration
+	// the kinds of failures to retry: exit, timeout, regex and probe
+	On []string
+}
+
+// Retryable returns true if a failure decided by condition is one of the kinds we retry.  Conditions inside of a group
+// (such as "all_of > exit") are judged by the condition that actually decided them.  An any_of group only fails once
+// every one of its groups has, so it names all of their conditions (as in "any_of > exit | timeout") and is retried
+// if any of them would be.  Failures of filemonitors count as regex, and failures of the http, tcp, exec and
+// healthcheck probes count as probe.
+func (r *Retry) Retryable(condition string) bool {
+	for _, branch := range strings.Split(condition, " | ") {
+		parts := strings.Split(branch, " > ")
+		decided := parts[len(parts)-1]
+		kind := decided
+		switch {
+		case strings.HasPrefix(decided, "filemonitor"):
+			kind = "regex"
+		case decided == "http" || decided == "tcp" || decided == "exec" || decided == "healthcheck":
+			kind = "probe"
+		}
+		for _, retryable := range r.On {
+			if retryable == kind {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Condition is a state condition that is parsed and run by the handler package.  Conditions are registered with
+// handler.RegisterCondition, and handler.Condition describes everything they need to provide.
+type Condition interface {
+	Describe() string
+}
+
 // StateConditions holds our conditions tht have been applied to services
 type StateConditions struct {
-	ExitCodes    *ExitCodes               `the exit code to expect. the value '-1' indicates that the process should not exit`
-	FileMonitors map[string][]FileMonitor `a map of map[filepath][]FileMonitor type to store filemonitors`
-	Timeout      *Timeout                 `how long we should wait (in seconds) for a success prior to automatically failing.`
+	// the conditions registered with the handler package, keyed by their state_conditions key
+	Conditions map[string]Condition
+	// groups of conditions that must all succeed
+	AllOf []StateConditions
+	// groups of conditions of which at least one must succeed
+	AnyOf []StateConditions
+	// groups of conditions that must succeed one after the other
+	Sequence []StateConditions
+	// how many times to recreate the service if its conditions fail
+	Retry *Retry
 }
 
 // Requires stores the requirements for each compose-file
@@ -0,0 +1,41 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/dansteen/controlled-compose/types"
+)
+
+var retryableTests = []struct {
+	condition string
+	on        []string
+	retryable bool
+}{
+	{condition: "exit", on: []string{"exit"}, retryable: true},
+	{condition: "exit", on: []string{"timeout"}, retryable: false},
+	{condition: "timeout", on: []string{"exit", "timeout"}, retryable: true},
+	{condition: "filemonitor STDOUT", on: []string{"regex"}, retryable: true},
+	{condition: "filemonitor /var/log/app.log", on: []string{"probe"}, retryable: false},
+	{condition: "http", on: []string{"probe"}, retryable: true},
+	{condition: "tcp", on: []string{"probe"}, retryable: true},
+	{condition: "exec", on: []string{"probe"}, retryable: true},
+	{condition: "healthcheck", on: []string{"probe"}, retryable: true},
+	{condition: "healthcheck", on: []string{"exit", "timeout", "regex"}, retryable: false},
+	{condition: "all_of > exit", on: []string{"exit"}, retryable: true},
+	{condition: "sequence > all_of > tcp", on: []string{"probe"}, retryable: true},
+	{condition: "sequence > timeout", on: []string{"exit"}, retryable: false},
+	{condition: "any_of > exit | timeout", on: []string{"timeout"}, retryable: true},
+	{condition: "any_of > exit | timeout", on: []string{"regex"}, retryable: false},
+	{condition: "any_of > all_of > exit | sequence > filemonitor STDERR", on: []string{"regex"}, retryable: true},
+	{condition: "all_of > any_of > http | any_of > tcp | exit", on: []string{"exit"}, retryable: true},
+	{condition: "exit", on: []string{}, retryable: false},
+}
+
+func TestRetryable(t *testing.T) {
+	for _, test := range retryableTests {
+		retry := &types.Retry{Attempts: 3, On: test.on}
+		if retryable := retry.Retryable(test.condition); retryable != test.retryable {
+			t.Errorf("expected %#v on %v to be retryable: %v but got %v", test.condition, test.on, test.retryable, retryable)
+		}
+	}
+}